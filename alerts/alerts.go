@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+type Kind string
+
+const (
+	WatchedUserBet Kind = "watched_user_bet"
+	BigBet         Kind = "big_bet"
+	BigBank        Kind = "big_bank"
+)
+
+type Alert struct {
+	Kind     Kind      `json:"kind"`
+	GameId   int       `json:"game_id"`
+	Room     string    `json:"room"`
+	UserId   int       `json:"user_id,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Amount   float32   `json:"amount"`
+	Bank     float32   `json:"bank"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+// Watcher checks new bets and games against the alert rules. Handlers run
+// under the client lock, so alerts are delivered from a separate goroutine.
+type Watcher struct {
+	config Config
+	sinks  []Sink
+	queue  chan *Alert
+	done   chan struct{}
+	// bankAlerted keeps the games that already raised a bank alert
+	bankAlerted map[int]bool
+}
+
+func NewWatcher(c *client.Client, config Config) *Watcher {
+	w := &Watcher{
+		config:      config,
+		queue:       make(chan *Alert, 100),
+		done:        make(chan struct{}),
+		bankAlerted: map[int]bool{},
+	}
+	if config.Sinks.Log {
+		w.sinks = append(w.sinks, LogSink{})
+	}
+	if config.Sinks.Chat {
+		w.sinks = append(w.sinks, ChatSink{Client: c})
+	}
+	if config.Sinks.Webhook != "" {
+		w.sinks = append(w.sinks, NewWebhookSink(config.Sinks.Webhook))
+	}
+	c.AddGameUpdateHandler(w.onGameUpdate)
+	go w.deliver()
+	return w
+}
+
+// AddSink delivers the alerts to one more sink, it must be called before
+// the client starts listening.
+func (w *Watcher) AddSink(sink Sink) {
+	w.sinks = append(w.sinks, sink)
+}
+
+func (w *Watcher) onGameUpdate(game *client.Game, reason client.GameUpdateReason) {
+	switch reason {
+	case client.GameNew:
+		w.checkBank(game)
+	case client.GameBet:
+		bet := game.LastBet()
+		if bet == nil || bet.Virtual {
+			return
+		}
+		if label, ok := w.config.Users[bet.UserId]; ok {
+			w.emit(&Alert{
+				Kind:     WatchedUserBet,
+				UserId:   bet.UserId,
+				Username: bet.Username,
+				Amount:   bet.Amount,
+				Time:     bet.Time,
+				Message:  fmt.Sprintf("%s (%s) bet %.2f in %s game %d", playerName(bet), label, bet.Amount, game.Room, game.Id),
+			}, game)
+		}
+		if w.config.BetOver > 0 && bet.Amount > w.config.BetOver {
+			w.emit(&Alert{
+				Kind:     BigBet,
+				UserId:   bet.UserId,
+				Username: bet.Username,
+				Amount:   bet.Amount,
+				Time:     bet.Time,
+				Message:  fmt.Sprintf("%s bet %.2f in %s game %d", playerName(bet), bet.Amount, game.Room, game.Id),
+			}, game)
+		}
+		w.checkBank(game)
+	case client.GameEnd:
+		delete(w.bankAlerted, game.Id)
+	}
+}
+
+func (w *Watcher) checkBank(game *client.Game) {
+	limit, ok := w.config.BankOver[game.Room]
+	bank := game.Bank - game.VirtualStake
+	if !ok || w.bankAlerted[game.Id] || bank <= limit {
+		return
+	}
+	w.bankAlerted[game.Id] = true
+	happened := game.Created
+	if bet := game.LastBet(); bet != nil {
+		happened = bet.Time
+	}
+	w.emit(&Alert{
+		Kind:    BigBank,
+		Amount:  bank,
+		Time:    happened,
+		Message: fmt.Sprintf("%s game %d bank is %.2f", game.Room, game.Id, bank),
+	}, game)
+}
+
+func (w *Watcher) emit(alert *Alert, game *client.Game) {
+	alert.GameId = game.Id
+	alert.Room = game.Room
+	alert.Bank = game.Bank - game.VirtualStake
+	select {
+	case w.queue <- alert:
+	default:
+		fmt.Println("alert queue is full, dropping", alert.Message)
+	}
+}
+
+// Close delivers the queued alerts and stops the watcher, it must be called
+// after the client stopped processing events.
+func (w *Watcher) Close() {
+	close(w.queue)
+	<-w.done
+}
+
+func (w *Watcher) deliver() {
+	defer close(w.done)
+	for alert := range w.queue {
+		for _, sink := range w.sinks {
+			if err := sink.Send(alert); err != nil {
+				fmt.Println("cannot deliver alert:", err)
+			}
+		}
+	}
+}
+
+func playerName(bet *client.Bet) string {
+	if bet.Username == "" {
+		return fmt.Sprintf("user %d", bet.UserId)
+	}
+	return bet.Username
+}
@@ -0,0 +1,36 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Config struct {
+	// Users maps watched user ids to a label used in the alert text
+	Users map[int]string `json:"users"`
+	// BetOver alerts on any bet larger than this amount, off when zero
+	BetOver float32 `json:"bet_over"`
+	// BankOver maps room names to the bank that raises an alert once per game
+	BankOver map[string]float32 `json:"bank_over"`
+	Sinks    SinksConfig        `json:"sinks"`
+}
+
+type SinksConfig struct {
+	Log bool `json:"log"`
+	// Chat posts the alert text to the site chat
+	Chat    bool   `json:"chat"`
+	Webhook string `json:"webhook"`
+}
+
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("cannot parse alerts config: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,57 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// Sink delivers alerts somewhere outside of the bot.
+type Sink interface {
+	Send(alert *Alert) error
+}
+
+type LogSink struct{}
+
+func (LogSink) Send(alert *Alert) error {
+	fmt.Println("alert:", alert.Message)
+	return nil
+}
+
+type ChatSink struct {
+	Client *client.Client
+}
+
+func (s ChatSink) Send(alert *Alert) error {
+	return s.Client.SendChat(alert.Message)
+}
+
+// WebhookSink posts the alert as json to the url.
+type WebhookSink struct {
+	Url        string
+	httpClient *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{Url: url, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *WebhookSink) Send(alert *Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Post(s.Url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package analytics
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+// betSizeBuckets are the upper bounds of the bet size histogram in units of
+// the room minimum bet, the last bucket is open.
+var betSizeBuckets = []float32{1, 2, 5, 10, 25, 50}
+
+type RoomReport struct {
+	RoomId     int
+	Room       string
+	Games      int
+	AvgBank    float32
+	AvgPlayers float32
+	Bets       int
+	// BetSizes counts bets by size, see BetSizeLabels
+	BetSizes []int
+	// Median and P90 of the bet size
+	MedianBet float32
+	P90Bet    float32
+	// GamesByHour counts games by the local hour they finished in
+	GamesByHour [24]int
+}
+
+type PlayerReport struct {
+	UserId   int
+	Username string
+	Games    int
+	Wagered  storage.Money
+	Wins     int
+	// ExpectedWins is the sum of the player's chances, a fair game has
+	// Wins close to it
+	ExpectedWins float64
+}
+
+func (p *PlayerReport) WinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.Games)
+}
+
+func (p *PlayerReport) ExpectedWinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return p.ExpectedWins / float64(p.Games)
+}
+
+// BetSizeLabels names the buckets of RoomReport.BetSizes.
+func BetSizeLabels() []string {
+	labels := []string{}
+	for i, bound := range betSizeBuckets {
+		if i == 0 {
+			labels = append(labels, "min")
+			continue
+		}
+		labels = append(labels, "≤"+formatMultiple(bound))
+	}
+	return append(labels, ">"+formatMultiple(betSizeBuckets[len(betSizeBuckets)-1]))
+}
+
+func formatMultiple(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32) + "x"
+}
+
+func betsByGame(bets []storage.BetRecord) map[int][]storage.BetRecord {
+	byGame := map[int][]storage.BetRecord{}
+	for _, bet := range bets {
+		if !bet.Virtual {
+			byGame[bet.GameId] = append(byGame[bet.GameId], bet)
+		}
+	}
+	return byGame
+}
+
+// Rooms reports every room of the catalogue, rooms without games included.
+func Rooms(games []storage.GameRecord, bets []storage.BetRecord, rooms client.Rooms) []*RoomReport {
+	reports := map[int]*RoomReport{}
+	for id, room := range rooms {
+		reports[id] = &RoomReport{RoomId: id, Room: room.Name, BetSizes: make([]int, len(betSizeBuckets)+1)}
+	}
+	byGame := betsByGame(bets)
+	amounts := map[int][]float32{}
+
+	for _, game := range games {
+		report, ok := reports[game.RoomId]
+		if !ok {
+			report = &RoomReport{RoomId: game.RoomId, Room: game.Room, BetSizes: make([]int, len(betSizeBuckets)+1)}
+			reports[game.RoomId] = report
+		}
+		report.Games++
+		report.AvgBank += game.Bank.Float()
+		report.AvgPlayers += float32(game.Players)
+		report.GamesByHour[game.Finished.Hour()]++
+
+		minBet := float32(1)
+		if room, ok := rooms[game.RoomId]; ok && room.MinBet > 0 {
+			minBet = room.MinBet
+		}
+		for _, bet := range byGame[game.Id] {
+			amount := bet.Amount.Float()
+			report.Bets++
+			report.BetSizes[bucket(amount/minBet)]++
+			amounts[game.RoomId] = append(amounts[game.RoomId], amount)
+		}
+	}
+
+	list := []*RoomReport{}
+	for id, report := range reports {
+		if report.Games > 0 {
+			report.AvgBank /= float32(report.Games)
+			report.AvgPlayers /= float32(report.Games)
+		}
+		sorted := amounts[id]
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		report.MedianBet = percentile(sorted, 50)
+		report.P90Bet = percentile(sorted, 90)
+		list = append(list, report)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RoomId < list[j].RoomId })
+	return list
+}
+
+func bucket(multiple float32) int {
+	for i, bound := range betSizeBuckets {
+		if multiple <= bound+1e-4 {
+			return i
+		}
+	}
+	return len(betSizeBuckets)
+}
+
+func percentile(sorted []float32, p int) float32 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// Players reports every player that bet in the games, sorted by the amount
+// wagered.
+func Players(games []storage.GameRecord, bets []storage.BetRecord) []*PlayerReport {
+	reports := map[int]*PlayerReport{}
+	byGame := betsByGame(bets)
+
+	for _, game := range games {
+		totals := map[int]storage.Money{}
+		var bank storage.Money
+		for _, bet := range byGame[game.Id] {
+			totals[bet.UserId] += bet.Amount
+			bank += bet.Amount
+			report, ok := reports[bet.UserId]
+			if !ok {
+				report = &PlayerReport{UserId: bet.UserId}
+				reports[bet.UserId] = report
+			}
+			if bet.Username != "" {
+				report.Username = bet.Username
+			}
+		}
+		if bank <= 0 {
+			continue
+		}
+		for userId, total := range totals {
+			report := reports[userId]
+			report.Games++
+			report.Wagered += total
+			report.ExpectedWins += float64(total) / float64(bank)
+			if game.WinnerId == userId {
+				report.Wins++
+			}
+		}
+	}
+
+	list := []*PlayerReport{}
+	for _, report := range reports {
+		list = append(list, report)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Wagered != list[j].Wagered {
+			return list[i].Wagered > list[j].Wagered
+		}
+		return list[i].UserId < list[j].UserId
+	})
+	return list
+}
@@ -0,0 +1,144 @@
+package backtest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/strategy"
+)
+
+type MonteCarloConfig struct {
+	Room    *client.Room
+	Balance float32
+	Runs    int
+	// Games is the length of every simulated sequence
+	Games int
+	// the stake of the other players in a game is drawn uniformly from this
+	// range, limited to the room MaxBank less MinBet so we can still bet
+	OthersMin float32
+	OthersMax float32
+	// StopLoss ends a sequence once the loss reaches it, zero disables it
+	StopLoss float32
+	Seed     int64
+}
+
+type MonteCarloReport struct {
+	Runs int
+	// Percentiles of the final balance by percent
+	Percentiles map[int]float32
+	MeanFinal   float32
+	// Ruin is the share of runs that could no longer afford the minimum bet
+	Ruin float64
+	// StopLoss is the share of runs that hit the stop loss and
+	// GamesToStopLoss is the average number of games they took
+	StopLoss        float64
+	GamesToStopLoss float64
+}
+
+var reportPercentiles = []int{5, 25, 50, 75, 95}
+
+func (r *MonteCarloReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "runs %d, mean final balance %.2f\n", r.Runs, r.MeanFinal)
+	for _, p := range reportPercentiles {
+		fmt.Fprintf(&b, "p%-3d %.2f\n", p, r.Percentiles[p])
+	}
+	fmt.Fprintf(&b, "risk of ruin %.2f%%\n", r.Ruin*100)
+	fmt.Fprintf(&b, "stop loss hit %.2f%%, after %.1f games on average", r.StopLoss*100, r.GamesToStopLoss)
+	return b.String()
+}
+
+// MonteCarlo simulates sequences of jackpot games where we win with the
+// probability of our stake share and the winner gets the bank minus the
+// room commission.
+func MonteCarlo(s strategy.Strategy, config MonteCarloConfig) *MonteCarloReport {
+	rng := rand.New(rand.NewSource(config.Seed))
+	if limit := config.Room.MaxBank - config.Room.MinBet; config.OthersMax > limit {
+		config.OthersMax = limit
+	}
+	if config.OthersMin > config.OthersMax {
+		config.OthersMin = config.OthersMax
+	}
+	rooms := client.Rooms{config.Room.Id: config.Room}
+	finals := make([]float32, 0, config.Runs)
+	report := &MonteCarloReport{Runs: config.Runs, Percentiles: map[int]float32{}}
+	ruined, stopped, stopGames := 0, 0, 0
+
+	for run := 0; run < config.Runs; run++ {
+		balance := config.Balance
+		// the checks run once more after the last game
+		for i := 0; ; i++ {
+			if balance < config.Room.MinBet {
+				ruined++
+				break
+			}
+			if config.StopLoss > 0 && config.Balance-balance >= config.StopLoss {
+				stopped++
+				stopGames += i
+				break
+			}
+			if i == config.Games {
+				break
+			}
+			balance += simulateRandomGame(s, rooms, config, i, balance, rng)
+		}
+		finals = append(finals, balance)
+		report.MeanFinal += balance / float32(config.Runs)
+	}
+
+	sort.Slice(finals, func(i, j int) bool { return finals[i] < finals[j] })
+	for _, p := range reportPercentiles {
+		if len(finals) > 0 {
+			report.Percentiles[p] = finals[(len(finals)-1)*p/100]
+		}
+	}
+	if config.Runs > 0 {
+		report.Ruin = float64(ruined) / float64(config.Runs)
+		report.StopLoss = float64(stopped) / float64(config.Runs)
+	}
+	if stopped > 0 {
+		report.GamesToStopLoss = float64(stopGames) / float64(stopped)
+	}
+	return report
+}
+
+// simulateRandomGame returns the change of balance after one game.
+func simulateRandomGame(s strategy.Strategy, rooms client.Rooms, config MonteCarloConfig, id int, balance float32, rng *rand.Rand) float32 {
+	game, err := client.NewGame(id, config.Room.Id, rooms)
+	if err != nil {
+		return 0
+	}
+	others := config.OthersMin + rng.Float32()*(config.OthersMax-config.OthersMin)
+	game.AddBet(client.Bet{Amount: others})
+	game.Bank = others
+	game.State = client.GameStateBetting
+
+	reason := client.GameBet
+	if _, ok := s.(strategy.Timed); ok {
+		reason = client.GameTime
+		game.State = client.GameStateCountdown
+		game.TimeNow = 0
+	}
+	for _, decision := range s.Decide(game, reason, balance) {
+		if decision.Amount <= 0 || decision.Amount > balance-game.VirtualStake {
+			continue
+		}
+		if err := game.AddVirtualBet(simulatedUserId, decision.Amount, game.Created); err != nil {
+			break
+		}
+	}
+	if game.VirtualStake <= 0 {
+		return 0
+	}
+
+	game.Won = rng.Float32() < game.VirtualStake/game.Bank
+	game.State = client.GameStateFinished
+	s.Decide(game, client.GameEnd, balance)
+	if game.Won {
+		return game.WinAmount() - game.VirtualStake
+	}
+	return -game.VirtualStake
+}
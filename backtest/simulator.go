@@ -0,0 +1,192 @@
+package backtest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/strategy"
+)
+
+// simulatedUserId marks our virtual bets, no real user has it.
+const simulatedUserId = -1
+
+type Config struct {
+	Rooms   client.Rooms
+	Balance float32
+	// RecordedResult settles with the recorded winning ticket, otherwise
+	// every game is drawn with our share of the bank as win probability
+	RecordedResult bool
+	Seed           int64
+}
+
+// Result is the performance of a strategy in one room, Room is empty for
+// the total over all rooms.
+type Result struct {
+	Strategy    string
+	Room        string
+	Games       int
+	Played      int
+	Wins        int
+	Bets        int
+	Wagered     float32
+	PnL         float32
+	MaxDrawdown float32
+
+	peak float32
+}
+
+func (r *Result) HitRate() float32 {
+	if r.Played == 0 {
+		return 0
+	}
+	return float32(r.Wins) / float32(r.Played)
+}
+
+func (r *Result) String() string {
+	room := r.Room
+	if room == "" {
+		room = "total"
+	}
+	return fmt.Sprintf("%-28s %-8s games %5d played %5d bets %5d wagered %10.2f pnl %10.2f drawdown %9.2f hit %5.1f%%",
+		r.Strategy, room, r.Games, r.Played, r.Bets, r.Wagered, r.PnL, r.MaxDrawdown, r.HitRate()*100)
+}
+
+func (r *Result) add(stake float32, profit float32, won bool, bets int) {
+	r.Games++
+	if stake <= 0 {
+		return
+	}
+	r.Played++
+	r.Bets += bets
+	r.Wagered += stake
+	r.PnL += profit
+	if won {
+		r.Wins++
+	}
+	if r.PnL > r.peak {
+		r.peak = r.PnL
+	}
+	if drawdown := r.peak - r.PnL; drawdown > r.MaxDrawdown {
+		r.MaxDrawdown = drawdown
+	}
+}
+
+// Run plays the timelines in order with the strategy and returns the total
+// result followed by the results per room.
+func Run(s strategy.Strategy, timelines []*Timeline, config Config) []*Result {
+	rooms := config.Rooms
+	if rooms == nil {
+		rooms = client.DefaultRooms()
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+	balance := config.Balance
+	total := &Result{Strategy: s.Name()}
+	byRoom := map[string]*Result{}
+
+	for _, timeline := range timelines {
+		game, err := client.NewGame(timeline.GameId, timeline.RoomId, rooms)
+		if err != nil {
+			fmt.Println("backtest skips game", timeline.GameId, err)
+			continue
+		}
+		bets := simulateGame(s, game, timeline, &balance)
+
+		won := false
+		if game.VirtualStake > 0 {
+			if config.RecordedResult && timeline.Result != nil {
+				won = client.VirtualWin(game, game.VirtualStake)
+			} else {
+				won = rng.Float32() < game.VirtualStake/game.Bank
+			}
+		}
+		game.Won = won
+		game.State = client.GameStateFinished
+		var profit float32
+		if game.VirtualStake > 0 {
+			profit = -game.VirtualStake
+			if won {
+				profit += game.WinAmount()
+				balance += game.WinAmount()
+			}
+		}
+		s.Decide(game, client.GameEnd, balance)
+
+		room, ok := byRoom[game.Room]
+		if !ok {
+			room = &Result{Strategy: s.Name(), Room: game.Room}
+			byRoom[game.Room] = room
+		}
+		room.add(game.VirtualStake, profit, won, bets)
+		total.add(game.VirtualStake, profit, won, bets)
+	}
+
+	results := []*Result{total}
+	for _, result := range byRoom {
+		results = append(results, result)
+	}
+	sort.Slice(results[1:], func(i, j int) bool {
+		return results[i+1].Room < results[j+1].Room
+	})
+	return results
+}
+
+// simulateGame feeds the timeline to the strategy and places its bets into
+// the pot, it returns the number of bets placed.
+func simulateGame(s strategy.Strategy, game *client.Game, timeline *Timeline, balance *float32) int {
+	timed, isTimed := s.(strategy.Timed)
+	timedDone := false
+	placed := 0
+	now := timeline.Started
+
+	decide := func(reason client.GameUpdateReason) {
+		decisions := s.Decide(game, reason, *balance)
+		if isTimed {
+			// timed strategies bet once, at the first timer push inside their window
+			if reason != client.GameTime || timedDone || game.TimeNow > int(timed.BetBefore().Seconds()) {
+				return
+			}
+			timedDone = true
+		}
+		for _, decision := range decisions {
+			if decision.Amount <= 0 || decision.Amount > *balance {
+				continue
+			}
+			if err := game.AddVirtualBet(simulatedUserId, decision.Amount, now); err != nil {
+				break
+			}
+			*balance -= decision.Amount
+			placed++
+		}
+	}
+
+	decide(client.GameNew)
+	for _, event := range timeline.Events {
+		switch event.Reason {
+		case client.GameBet:
+			now = event.Bet.Time
+			game.Bank = event.Bank + game.VirtualStake
+			game.AddBet(*event.Bet)
+			if game.State < client.GameStateBetting {
+				game.State = client.GameStateBetting
+			}
+		case client.GameTime:
+			game.Bank = event.Bank + game.VirtualStake
+			game.TimeNow = event.TimeLeft
+			game.State = client.GameStateCountdown
+			if event.TimeLeft <= 0 {
+				game.State = client.GameStateDrawing
+			}
+		}
+		if game.AcceptsBets() {
+			decide(event.Reason)
+		}
+	}
+
+	game.Result = timeline.Result
+	if timeline.Result != nil && timeline.Result.Bank > 0 {
+		game.Bank = timeline.Result.Bank + game.VirtualStake
+	}
+	return placed
+}
@@ -0,0 +1,58 @@
+package backtest
+
+import (
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+// LoadStorage builds timelines from stored games. Stored games keep bets
+// and the result only, so timed strategies never reach their window there.
+func LoadStorage(db *storage.DB, filter storage.Filter) ([]*Timeline, error) {
+	games, err := db.Games(filter)
+	if err != nil {
+		return nil, err
+	}
+	timelines := []*Timeline{}
+	for _, game := range games {
+		bets, err := db.GameBets(game.Id)
+		if err != nil {
+			return nil, err
+		}
+		timeline := &Timeline{
+			GameId:  game.Id,
+			RoomId:  game.RoomId,
+			Started: game.Created,
+			Result: &client.EndGameEvent{
+				GameId:     game.Id,
+				WinnerId:   game.WinnerId,
+				WinnerName: game.WinnerName,
+				Ticket:     game.Ticket,
+				Bank:       game.Bank.Float(),
+				Commission: game.Commission.Float(),
+				Hash:       game.Hash,
+				Seed:       game.Seed,
+			},
+		}
+		var bank storage.Money
+		for _, bet := range bets {
+			if bet.Virtual {
+				continue
+			}
+			bank += bet.Amount
+			timeline.Events = append(timeline.Events, Event{
+				Reason: client.GameBet,
+				Bank:   bank.Float(),
+				Bet: &client.Bet{
+					UserId:     bet.UserId,
+					Username:   bet.Username,
+					Amount:     bet.Amount.Float(),
+					Time:       bet.Placed,
+					TicketFrom: bet.TicketFrom,
+					TicketTo:   bet.TicketTo,
+				},
+			})
+		}
+		timelines = append(timelines, timeline)
+	}
+	return timelines, nil
+}
@@ -0,0 +1,75 @@
+package backtest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// Event is a single update of a recorded game, either a bet or a timer push.
+type Event struct {
+	Reason   client.GameUpdateReason
+	Bet      *client.Bet
+	Bank     float32
+	TimeLeft int
+}
+
+// Timeline is everything that happened in one finished game.
+type Timeline struct {
+	GameId  int
+	RoomId  int
+	Started time.Time
+	Events  []Event
+	Result  *client.EndGameEvent
+}
+
+// LoadRecording replays a websocket recording through a client and collects
+// the timelines of the games that finished in it.
+func LoadRecording(path string, rooms client.Rooms) ([]*Timeline, error) {
+	open := map[int]*Timeline{}
+	timelines := []*Timeline{}
+
+	c := client.NewClient(client.ClientConfig{
+		Rooms: rooms,
+		GameUpdateHandler: func(game *client.Game, reason client.GameUpdateReason) {
+			switch reason {
+			case client.GameNew:
+				timeline := &Timeline{GameId: game.Id, RoomId: game.RoomId}
+				for i := range game.Bets {
+					bet := game.Bets[i]
+					timeline.Events = append(timeline.Events, Event{Reason: client.GameBet, Bet: &bet, Bank: game.Bank})
+				}
+				open[game.Id] = timeline
+			case client.GameBet:
+				if timeline, ok := open[game.Id]; ok {
+					bet := *game.LastBet()
+					timeline.Events = append(timeline.Events, Event{Reason: reason, Bet: &bet, Bank: game.Bank})
+				}
+			case client.GameTime:
+				if timeline, ok := open[game.Id]; ok {
+					timeline.Events = append(timeline.Events, Event{Reason: reason, Bank: game.Bank, TimeLeft: game.TimeNow})
+				}
+			case client.GameEnd:
+				if timeline, ok := open[game.Id]; ok {
+					timeline.Result = game.Result
+					timelines = append(timelines, timeline)
+					delete(open, game.Id)
+				}
+			}
+		},
+	})
+	if err := c.Replay(path, 0); err != nil {
+		return nil, err
+	}
+
+	for _, timeline := range timelines {
+		if len(timeline.Events) > 0 && timeline.Events[0].Bet != nil {
+			timeline.Started = timeline.Events[0].Bet.Time
+		}
+	}
+	sort.SliceStable(timelines, func(i, j int) bool {
+		return timelines[i].Started.Before(timelines[j].Started)
+	})
+	return timelines, nil
+}
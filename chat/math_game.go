@@ -1,50 +1,167 @@
 package chat
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	csgf_client "github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/metrics"
+	"github.com/Qwerty10291/csgf_bot/store"
 	"github.com/Qwerty10291/csgf_bot/utils"
 )
 
+const payoutRetryInterval = time.Minute
+
 type mathGame struct {
-	creator    string
-	expression string
-	answer     int
-	bank       float32
+	Creator    string  `json:"creator"`
+	Expression string  `json:"expression"`
+	Answer     int     `json:"answer"`
+	Bank       float32 `json:"bank"`
 }
 
 func (g *mathGame) message() string {
-	return fmt.Sprintf("%s, создал пример переведя на этот аккаунт %.2f. Пример:%s", g.creator, g.bank, g.expression)
+	return fmt.Sprintf("%s, создал пример переведя на этот аккаунт %.2f. Пример:%s", g.Creator, g.Bank, g.Expression)
+}
+
+type mathPayout struct {
+	UserId   int     `json:"user_id"`
+	Username string  `json:"username"`
+	Amount   float32 `json:"amount"`
+	// Attempts counts the transfers the site refused
+	Attempts int `json:"attempts"`
+}
+
+// MathRound is an entry of the rounds log, written when the round is solved
+// and before the winner is paid.
+type MathRound struct {
+	Creator    string    `json:"creator"`
+	Expression string    `json:"expression"`
+	Answer     int       `json:"answer"`
+	Bank       float32   `json:"bank"`
+	WinnerId   int       `json:"winner_id"`
+	Winner     string    `json:"winner"`
+	Solved     time.Time `json:"solved"`
+}
+
+// ReadMathRounds returns the logged rounds solved in [from, to), zero
+// times are not applied.
+func ReadMathRounds(s store.Store, from time.Time, to time.Time) ([]MathRound, error) {
+	rounds := []MathRound{}
+	err := s.ReadLog("rounds", func(entry json.RawMessage) error {
+		var round MathRound
+		if err := json.Unmarshal(entry, &round); err != nil {
+			return err
+		}
+		if (from.IsZero() || !round.Solved.Before(from)) && (to.IsZero() || round.Solved.Before(to)) {
+			rounds = append(rounds, round)
+		}
+		return nil
+	})
+	return rounds, err
+}
+
+// MathPayoutFailure is a payout whose transfer failed. A payout the site
+// refused is retried later, an Uncertain one may have been sent and is
+// parked for a manual check.
+type MathPayoutFailure struct {
+	UserId    int
+	Username  string
+	Amount    float32
+	Err       error
+	Uncertain bool
+}
+
+type MathRoundHandler func(*MathRound)
+type MathPayoutFailedHandler func(*MathPayoutFailure)
+
+type mathGameState struct {
+	CurrentGame    *mathGame     `json:"current_game"`
+	GamesQueue     []*mathGame   `json:"games_queue"`
+	PendingPayouts []*mathPayout `json:"pending_payouts"`
+	// UncertainPayouts were sent without a clear answer, or were in flight
+	// when the bot stopped, they are never sent again automatically
+	UncertainPayouts []*mathPayout `json:"uncertain_payouts"`
 }
 
 type MathChatGame struct {
-	client      *csgf_client.Client
-	comission   float32
-	currentGame *mathGame
-	gamesQueue  []*mathGame
+	client    *csgf_client.Client
+	comission float32
+	store     store.Store
+
+	mu             sync.Mutex
+	currentGame    *mathGame
+	gamesQueue     []*mathGame
+	pendingPayouts []*mathPayout
+	// uncertainPayouts also holds the payouts in flight
+	uncertainPayouts []*mathPayout
+	payoutWake       chan struct{}
+
+	roundHandlers        []MathRoundHandler
+	payoutFailedHandlers []MathPayoutFailedHandler
 }
 
-func NewMathChatGame(client *csgf_client.Client, comission float32) *MathChatGame {
+// NewMathChatGame restores the queue and unpaid winners from the store, a
+// nil store keeps them in memory.
+func NewMathChatGame(client *csgf_client.Client, comission float32, s store.Store) *MathChatGame {
+	if s == nil {
+		s = store.NewMemory()
+	}
 	game := &MathChatGame{
 		client:     client,
 		comission:  comission,
+		store:      s,
 		gamesQueue: []*mathGame{},
+		payoutWake: make(chan struct{}, 1),
+	}
+	var state mathGameState
+	if ok, err := s.Get("state", &state); err != nil {
+		fmt.Println("math game state load error", err)
+	} else if ok {
+		game.currentGame = state.CurrentGame
+		game.gamesQueue = append(game.gamesQueue, state.GamesQueue...)
+		game.pendingPayouts = state.PendingPayouts
+		game.uncertainPayouts = state.UncertainPayouts
+	}
+	for _, payout := range game.uncertainPayouts {
+		fmt.Printf("payout %.2f to %s (%d) needs a manual check\n", payout.Amount, payout.Username, payout.UserId)
 	}
+
 	client.ChatUpdateHandler = game.messagesProcessor
 	client.TransferEventHandler = game.transferHandler
 	go game.adversion()
+	go game.payoutSender()
+	game.wakePayouts()
 	return game
 }
 
+// AddRoundHandler registers a handler called when a round is solved.
+func (g *MathChatGame) AddRoundHandler(handler MathRoundHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.roundHandlers = append(g.roundHandlers, handler)
+}
+
+// AddPayoutFailedHandler registers a handler called on the first refusal of
+// a payout and when a payout becomes uncertain.
+func (g *MathChatGame) AddPayoutFailedHandler(handler MathPayoutFailedHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.payoutFailedHandlers = append(g.payoutFailedHandlers, handler)
+}
+
 func (g *MathChatGame) messagesProcessor(msg *csgf_client.ChatEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	if g.currentGame != nil {
 		answer, err := strconv.Atoi(msg.Message)
-		if err == nil && answer == g.currentGame.answer {
-			g.client.SendChatMessage(fmt.Sprintf("Победитель: %s", msg.Username))
-			g.client.SendTransfer(msg.UserId, g.currentGame.bank)
+		if err == nil && answer == g.currentGame.Answer {
+			g.sendChat(fmt.Sprintf("Победитель: %s", msg.Username))
+			g.payWinner(g.currentGame, msg.UserId, msg.Username, msg.Time)
 
 			if len(g.gamesQueue) > 0 {
 				game := g.gamesQueue[0]
@@ -53,12 +170,16 @@ func (g *MathChatGame) messagesProcessor(msg *csgf_client.ChatEvent) {
 			} else {
 				g.currentGame = nil
 			}
+			g.saveState()
 		}
 	}
 }
 
 func (g *MathChatGame) transferHandler(transfer *csgf_client.NotifyEventTransfer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.newGame(transfer.FromUser, transfer.Amount)
+	g.saveState()
 }
 
 func (g *MathChatGame) newGame(creator string, bank float32) {
@@ -69,10 +190,10 @@ func (g *MathChatGame) newGame(creator string, bank float32) {
 
 	expr, answer := utils.MathematicExpressionGenerator()
 	game := &mathGame{
-		creator:    creator,
-		expression: expr,
-		answer:     answer,
-		bank:       bank,
+		Creator:    creator,
+		Expression: expr,
+		Answer:     answer,
+		Bank:       bank,
 	}
 
 	if g.currentGame == nil {
@@ -83,15 +204,134 @@ func (g *MathChatGame) newGame(creator string, bank float32) {
 }
 
 func (g *MathChatGame) startGame(game *mathGame) {
-	fmt.Println("new game", game.bank)
+	fmt.Println("new game", game.Bank)
+	metrics.MathGamesStarted.Inc()
 	g.currentGame = game
-	g.client.SendChatMessage(game.message())
+	g.sendChat(game.message())
+}
+
+// payWinner records the payout and leaves the transfer to payoutSender, so
+// the payout is not lost when the transfer fails.
+func (g *MathChatGame) payWinner(game *mathGame, userId int, username string, solved time.Time) {
+	payout := &mathPayout{UserId: userId, Username: username, Amount: game.Bank}
+	g.pendingPayouts = append(g.pendingPayouts, payout)
+	g.saveState()
+
+	metrics.MathGamesSolved.Inc()
+	round := &MathRound{
+		Creator:    game.Creator,
+		Expression: game.Expression,
+		Answer:     game.Answer,
+		Bank:       game.Bank,
+		WinnerId:   userId,
+		Winner:     username,
+		Solved:     solved,
+	}
+	if err := g.store.Append("rounds", round); err != nil {
+		fmt.Println("math game rounds log error", err)
+	}
+	for _, handler := range g.roundHandlers {
+		handler(round)
+	}
+	g.wakePayouts()
+}
+
+func (g *MathChatGame) wakePayouts() {
+	select {
+	case g.payoutWake <- struct{}{}:
+	default:
+	}
+}
+
+// payoutSender sends the payouts outside of the game lock, so a slow
+// transfer does not block the chat handler and with it the client.
+func (g *MathChatGame) payoutSender() {
+	ticker := time.NewTicker(payoutRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.payoutWake:
+		case <-ticker.C:
+		}
+		g.sendPayouts()
+	}
+}
+
+// sendPayouts tries every pending payout once. A payout is parked as
+// uncertain while its transfer is in flight, only an explicit refusal of
+// the site returns it to the pending ones, so a winner is never paid twice.
+func (g *MathChatGame) sendPayouts() {
+	g.mu.Lock()
+	batch := g.pendingPayouts
+	g.pendingPayouts = nil
+	g.uncertainPayouts = append(g.uncertainPayouts, batch...)
+	if len(batch) > 0 {
+		g.saveState()
+	}
+	g.mu.Unlock()
+
+	for _, payout := range batch {
+		err := g.client.SendTransfer(payout.UserId, payout.Amount)
+		g.mu.Lock()
+		g.finishPayout(payout, err)
+		g.mu.Unlock()
+	}
+}
+
+func (g *MathChatGame) finishPayout(payout *mathPayout, err error) {
+	var rejected *csgf_client.TransferRejectedError
+	uncertain := err != nil && !errors.As(err, &rejected)
+	if !uncertain {
+		for i, pending := range g.uncertainPayouts {
+			if pending == payout {
+				g.uncertainPayouts = append(g.uncertainPayouts[:i], g.uncertainPayouts[i+1:]...)
+				break
+			}
+		}
+	}
+	if err != nil {
+		if uncertain {
+			fmt.Println("payout to", payout.Username, "is uncertain, check it manually:", err)
+		} else {
+			fmt.Println("payout to", payout.Username, "refused, retrying later:", err)
+			payout.Attempts++
+			g.pendingPayouts = append(g.pendingPayouts, payout)
+		}
+		// handlers hear about the first refusal only, not every retry
+		if uncertain || payout.Attempts == 1 {
+			failure := &MathPayoutFailure{UserId: payout.UserId, Username: payout.Username, Amount: payout.Amount, Err: err, Uncertain: uncertain}
+			for _, handler := range g.payoutFailedHandlers {
+				handler(failure)
+			}
+		}
+	}
+	g.saveState()
+}
+
+func (g *MathChatGame) saveState() {
+	metrics.MathGamesQueued.Set(float64(len(g.gamesQueue)))
+	err := g.store.Set("state", mathGameState{
+		CurrentGame:      g.currentGame,
+		GamesQueue:       g.gamesQueue,
+		PendingPayouts:   g.pendingPayouts,
+		UncertainPayouts: g.uncertainPayouts,
+	})
+	if err != nil {
+		fmt.Println("math game state save error", err)
+	}
+}
+
+// sendChat logs a failed message, the game goes on without it.
+func (g *MathChatGame) sendChat(msg string) {
+	if err := g.client.SendChat(msg); err != nil {
+		fmt.Println("math game chat message error", err)
+	}
 }
 
 func (g *MathChatGame) adversion() {
 	for {
 		time.Sleep(time.Minute * 4)
-		g.client.SendChatMessage("Вы можете воспользоваться функцией автоматического создания розыгрыша с примером, переведя на этот аккаунт любую сумму")
+		g.sendChat("Вы можете воспользоваться функцией автоматического создания розыгрыша с примером, переведя на этот аккаунт любую сумму")
 	}
 } 
 
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	pageGameRegexp = regexp.MustCompile(`game_(\d+)`)
+	pageBankRegexp = regexp.MustCompile(`<span class="bank">(.+?)<`)
+	pageTimeRegexp = regexp.MustCompile(`data-time="(\d+)"`)
+	pageBetRegexp  = regexp.MustCompile(`<a href="\/user\/(\d+)">`)
+	pageSumRegexp  = regexp.MustCompile(`<span class="sum">(.+?) <`)
+	pageNameRegexp = regexp.MustCompile(`class="name"[^>]*>(.+?)<`)
+
+	pageTicketsRegexp = regexp.MustCompile(`#(\d+)\s*-\s*#(\d+)`)
+
+	resultWinnerRegexp = regexp.MustCompile(`class="winner"[\s\S]*?<a href="\/user\/(\d+)">[\s\S]*?class="name"[^>]*>(.+?)<`)
+	resultTicketRegexp = regexp.MustCompile(`class="ticket"[^>]*>\D*(\d+)`)
+	resultHashRegexp   = regexp.MustCompile(`data-hash="(\w+)"`)
+	resultSeedRegexp   = regexp.MustCompile(`data-seed="(\w+)"`)
+)
+
+func roomUrl(roomName string) string {
+	return "https://csgf.live/" + roomName
+}
+
+// GameUrl is the page of a single game, it shows the result once the game
+// is finished.
+func GameUrl(gameId int) string {
+	return fmt.Sprintf("https://csgf.live/game/%d", gameId)
+}
+
+// bootstrapGames loads the running game of every room from the site pages,
+// so bets for games started before we connected are not lost.
+func (c *Client) bootstrapGames() error {
+	running := map[int]bool{}
+	var errs []string
+	for roomId, room := range c.rooms {
+		roomName := room.Name
+		html, err := c.getPage(roomUrl(roomName))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		game, err := parseRoomPage(roomId, html, c.rooms)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", roomName, err))
+			continue
+		}
+		running[game.Id] = true
+		c.seedGame(game)
+	}
+
+	// games finished while we were disconnected will never get end_game
+	for id, game := range c.openedGames {
+		if !running[id] && len(errs) == 0 {
+			c.finishStaleGame(game)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// finishStaleGame ends a game that finished while we were disconnected,
+// the result is loaded from the game page and goes the normal end path.
+func (c *Client) finishStaleGame(game *Game) {
+	html, err := c.getPage(GameUrl(game.Id))
+	if err == nil {
+		var event *EndGameEvent
+		if event, err = ParseGameResult(game.Id, html); err == nil {
+			fmt.Println("finishing stale game", game.Id, game.Room)
+			c.processEndGameEvent(event)
+			return
+		}
+	}
+	fmt.Println("cannot load the result of stale game", game.Id, err)
+	delete(c.openedGames, game.Id)
+	c.cancelScheduled(game.Id)
+	c.dropPendingBets(game)
+	game.State = GameStateFinished
+	game.Finished = c.now()
+	// without a result our stake counts as lost, so a reconnect cannot get
+	// around the loss limits
+	c.settle(game)
+	c.callGameUpdate(game, GameEnd)
+}
+
+// seedGame merges a game loaded from the site into openedGames, keeping
+// our own stake when the game is already known.
+func (c *Client) seedGame(game *Game) {
+	known, ok := c.openedGames[game.Id]
+	if !ok {
+		game.clock = c.now
+		game.BetNow = game.UserTotal(c.UserId)
+		c.openedGames[game.Id] = game
+		c.callGameUpdate(game, GameNew)
+		return
+	}
+	if known.State < game.State {
+		known.State = game.State
+	}
+	// the page shows the real pot only, our paper bets stay on top of it
+	known.Bank = game.Bank + known.VirtualStake
+	known.TimeNow = game.TimeNow
+	known.mergeBets(game.Bets)
+	c.reconcileBetNow(known)
+}
+
+// mergeBets appends the page bets the game does not know yet, the known
+// ones keep their times. The page has no bet ids, bets are matched by user
+// and amount; virtual bets are never on the page and always stay.
+func (g *Game) mergeBets(bets []Bet) {
+	known := map[pageBetKey]int{}
+	for _, bet := range g.Bets {
+		if !bet.Virtual {
+			known[betKey(bet)]++
+		}
+	}
+	for _, bet := range bets {
+		key := betKey(bet)
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+		g.AddBet(bet)
+	}
+}
+
+type pageBetKey struct {
+	userId int
+	cents  int64
+}
+
+func betKey(bet Bet) pageBetKey {
+	return pageBetKey{userId: bet.UserId, cents: int64(math.Round(float64(bet.Amount) * 100))}
+}
+
+func parseRoomPage(roomId int, html string, rooms Rooms) (*Game, error) {
+	gameData := pageGameRegexp.FindStringSubmatch(html)
+	if gameData == nil {
+		return nil, fmt.Errorf("cannot find game id")
+	}
+	gameId, err := strconv.Atoi(gameData[1])
+	if err != nil {
+		return nil, err
+	}
+	game, err := NewGame(gameId, roomId, rooms)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeData := pageTimeRegexp.FindStringSubmatch(html); timeData != nil {
+		game.TimeNow, _ = strconv.Atoi(timeData[1])
+	}
+
+	bets := ParsePageBets(html)
+	for _, bet := range bets {
+		game.AddBet(bet)
+	}
+	if len(bets) > 0 {
+		game.State = GameStateBetting
+	}
+
+	game.Bank = ParsePageBank(html)
+	if game.Bank == 0 {
+		for _, total := range game.Totals {
+			game.Bank += total
+		}
+	}
+	return game, nil
+}
+
+// ParsePageBets finds the bet blocks of a game page, oldest bet first.
+func ParsePageBets(html string) []Bet {
+	// every bet block starts with the user link, the page lists newest first
+	links := pageBetRegexp.FindAllStringSubmatchIndex(html, -1)
+	bets := []Bet{}
+	for i := len(links) - 1; i >= 0; i-- {
+		link := links[i]
+		end := len(html)
+		if i+1 < len(links) {
+			end = links[i+1][0]
+		}
+		block := html[link[0]:end]
+		sumData := pageSumRegexp.FindStringSubmatch(block)
+		if sumData == nil {
+			continue
+		}
+		amount, err := strconv.ParseFloat(sumData[1], 32)
+		if err != nil {
+			continue
+		}
+		userId, _ := strconv.Atoi(html[link[2]:link[3]])
+		bet := Bet{UserId: userId, Amount: float32(amount), Time: time.Now()}
+		if nameData := pageNameRegexp.FindStringSubmatch(block); nameData != nil {
+			bet.Username = nameData[1]
+		}
+		if ticketsData := pageTicketsRegexp.FindStringSubmatch(block); ticketsData != nil {
+			bet.TicketFrom, _ = strconv.Atoi(ticketsData[1])
+			bet.TicketTo, _ = strconv.Atoi(ticketsData[2])
+		}
+		bets = append(bets, bet)
+	}
+	return bets
+}
+
+// ParsePageBank returns the bank shown on a game page or zero.
+func ParsePageBank(html string) float32 {
+	bankData := pageBankRegexp.FindStringSubmatch(html)
+	if bankData == nil {
+		return 0
+	}
+	bank, err := strconv.ParseFloat(strings.TrimSpace(bankData[1]), 32)
+	if err != nil {
+		return 0
+	}
+	return float32(bank)
+}
+
+// FetchPage loads a page of the site with the session of the client.
+func (c *Client) FetchPage(url string) (string, error) {
+	return c.getPage(url)
+}
+
+// ParseGameResult reads the result from the page of a finished game.
+func ParseGameResult(gameId int, html string) (*EndGameEvent, error) {
+	winnerData := resultWinnerRegexp.FindStringSubmatch(html)
+	if winnerData == nil {
+		return nil, fmt.Errorf("cannot find winner, game is not finished")
+	}
+	event := &EndGameEvent{GameId: gameId, WinnerName: winnerData[2]}
+	event.WinnerId, _ = strconv.Atoi(winnerData[1])
+	if ticketData := resultTicketRegexp.FindStringSubmatch(html); ticketData != nil {
+		event.Ticket, _ = strconv.Atoi(ticketData[1])
+	}
+	if hashData := resultHashRegexp.FindStringSubmatch(html); hashData != nil {
+		event.Hash = hashData[1]
+	}
+	if seedData := resultSeedRegexp.FindStringSubmatch(html); seedData != nil {
+		event.Seed = seedData[1]
+	}
+	event.Bank = ParsePageBank(html)
+	if event.Bank == 0 {
+		for _, bet := range ParsePageBets(html) {
+			event.Bank += bet.Amount
+		}
+	}
+	return event, nil
+}
@@ -12,14 +12,20 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Qwerty10291/csgf_bot/metrics"
 	"github.com/gorilla/websocket"
 )
 
 
 var	messageSendInterval = time.Second
 
+// httpTimeout bounds every request to the site, a hung request must not
+// hold the client lock forever.
+const httpTimeout = 30 * time.Second
+
 type csgfWebsocketAuthRequest struct {
 	Params map[string]string `json:"params"`
 	Id     int               `json:"id"`
@@ -59,6 +65,10 @@ type GameUpdateReason int
 
 type ChatUpdateHandler func(*ChatEvent)
 type TransferEventHandler func(*NotifyEventTransfer)
+type BetRejectedHandler func(*Game, *RiskError)
+type SettlementHandler func(*Settlement)
+type BetConfirmHandler func(*BetConfirmation)
+type DisconnectHandler func(error)
 
 type ClientConfig struct {
 	VkLogin              string
@@ -66,6 +76,27 @@ type ClientConfig struct {
 	GameUpdateHandler    GameUpdateHandler
 	ChatUpdateHandler    ChatUpdateHandler
 	TransferEventHandler TransferEventHandler
+	BetRejectedHandler   BetRejectedHandler
+	SettlementHandler    SettlementHandler
+	BetConfirmHandler    BetConfirmHandler
+	BalanceUpdateHandler BalanceUpdateHandler
+	// DisconnectHandler is called when the websocket breaks, before reconnecting
+	DisconnectHandler DisconnectHandler
+	// BetConfirmTimeout is how long a bet may miss from the new_bet stream
+	// before it is reported unconfirmed, five seconds when zero
+	BetConfirmTimeout time.Duration
+	// Risk checks every bet before it is sent when set
+	Risk *RiskManager
+	// DryRun records virtual bets against a virtual balance instead of
+	// sending them, PaperBalance is the starting balance (the real one when zero)
+	DryRun       bool
+	PaperBalance float32
+	// Recorder receives every raw websocket frame when set
+	Recorder *Recorder
+	// Rooms is the room catalogue, DefaultRooms is used when nil
+	Rooms Rooms
+	// ScrapeRooms refreshes the catalogue from the site on connect
+	ScrapeRooms bool
 }
 
 type Client struct {
@@ -74,76 +105,168 @@ type Client struct {
 	websocket     *websocket.Conn
 	Balance       float32
 	UserId        int
-	channelNotify string
+	channelNotify  string
+	channelBalance string
 
+	rooms           Rooms
 	openedGames     map[int]*Game
+	pendingEvents   map[int][]bufferedEvent
 	lastMessageTime time.Time
+	replaying       bool
+	replayTime      time.Time
+	paperStarted    bool
+
+	// chatMu serializes chat messages for the rate limit
+	chatMu sync.Mutex
+
+	// mu serializes event processing with scheduled actions
+	mu         sync.Mutex
+	scheduled  map[int][]*scheduledAction
+	latencyMu  sync.Mutex
+	betLatency time.Duration
+	// bets accepted by the site and not yet seen in new_bet, by game id
+	pendingBets map[int][]*pendingBet
+
+	gameUpdateHandlers []GameUpdateHandler
+	settlementHandlers []SettlementHandler
+	betConfirmHandlers []BetConfirmHandler
+	balanceHandlers    []BalanceUpdateHandler
+	chatHandlers       []ChatUpdateHandler
+	transferHandlers   []TransferEventHandler
+	disconnectHandlers []DisconnectHandler
 }
 
 func NewClient(config ClientConfig) *Client {
 	jar, _ := cookiejar.New(nil)
-	client := http.Client{Jar: jar}
+	client := http.Client{Jar: jar, Timeout: httpTimeout}
+	rooms := config.Rooms
+	if rooms == nil {
+		rooms = DefaultRooms()
+	}
 
 	return &Client{
-		ClientConfig: config,
-		httpClient:   &client,
-		openedGames:  map[int]*Game{},
+		ClientConfig:  config,
+		httpClient:    &client,
+		rooms:         rooms,
+		openedGames:   map[int]*Game{},
+		pendingEvents: map[int][]bufferedEvent{},
+		scheduled:     map[int][]*scheduledAction{},
+		pendingBets:   map[int][]*pendingBet{},
 	}
 }
 
 func (c *Client) StartListener() {
-	var resp csgfWebsocketResponse
-
 	for {
-		err := c.websocket.ReadJSON(&resp)
+		_, frame, err := c.websocket.ReadMessage()
 		if err != nil {
 			fmt.Println("listener err", err)
+			c.callDisconnect(err)
+			c.reconnect()
+			continue
 		}
-		switch resp.Result.Channel {
-		case "new_game":
-			newGameEvent, err := NewGameEventFromJson(resp.Result.Data)
-			if err != nil {
-				fmt.Println("new game event parse error", err)
-				continue
-			}
-			c.processNewGameEvent(newGameEvent)
-		case "end_game":
-			event, err := EndGameEventFromJson(resp.Result.Data)
-			if err != nil {
-				fmt.Println("end game event parse error", err)
-				continue
-			}
-			c.processEndGameEvent(event)
-		case "new_bet":
-			event, err := NewBetEventFromJson(resp.Result.Data)
-			if err != nil {
-				fmt.Println("new bet event parse error", err)
-				continue
-			}
-			c.processNewBetEvent(event)
-		case "chat_new":
-			event, err := ChatEventFromJson(resp.Result.Data)
-			if err != nil {
-				fmt.Println("message parse error", err)
-				continue
-			}
-			if c.ChatUpdateHandler != nil {
-				c.ChatUpdateHandler(event)
-			}
-		case c.channelNotify:
-			notifyType, notifyData, err := NotifyEventFromJson(resp.Result.Data)
-			if err != nil {
-				fmt.Println("notify event parse error", err)
-				continue
-			}
-			switch notifyType {
-			case NotifyTransfer:
-				if c.TransferEventHandler != nil {
-					transferEvent, _ := notifyData.(NotifyEventTransfer)
-					c.TransferEventHandler(&transferEvent)
-				}
+		if c.Recorder != nil {
+			if err := c.Recorder.Write(frame); err != nil {
+				fmt.Println("recorder err", err)
 			}
 		}
+		c.processFrame(frame)
+	}
+}
+
+// processFrame dispatches every reply of a websocket frame, the server may
+// batch several newline separated replies into one frame.
+func (c *Client) processFrame(frame []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	decoder := json.NewDecoder(bytes.NewReader(frame))
+	for {
+		var resp csgfWebsocketResponse
+		err := decoder.Decode(&resp)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Println("frame decode error", err)
+			return
+		}
+		c.processResponse(&resp)
+	}
+}
+
+func (c *Client) processResponse(resp *csgfWebsocketResponse) {
+	// replies to our commands have no channel, during replay without a user
+	// id they would match the empty notify and balance channels
+	if resp.Result.Channel == "" {
+		return
+	}
+	metrics.Event(resp.Result.Channel)
+	switch resp.Result.Channel {
+	case "new_game":
+		newGameEvent, err := NewGameEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("new game event parse error", err)
+			metrics.ParseFailures.WithLabelValues("NewGameEventFromJson").Inc()
+			return
+		}
+		c.processNewGameEvent(newGameEvent)
+	case "time_game":
+		event, err := TimeEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("time event parse error", err)
+			metrics.ParseFailures.WithLabelValues("TimeEventFromJson").Inc()
+			return
+		}
+		c.processTimeEvent(event)
+	case "end_game":
+		event, err := EndGameEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("end game event parse error", err)
+			metrics.ParseFailures.WithLabelValues("EndGameEventFromJson").Inc()
+			return
+		}
+		c.processEndGameEvent(event)
+	case "new_bet":
+		event, err := NewBetEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("new bet event parse error", err)
+			metrics.ParseFailures.WithLabelValues("NewBetEventFromJson").Inc()
+			return
+		}
+		c.processNewBetEvent(event)
+	case "chat_new":
+		event, err := ChatEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("message parse error", err)
+			metrics.ParseFailures.WithLabelValues("ChatEventFromJson").Inc()
+			return
+		}
+		event.Time = c.now()
+		c.callChatUpdate(event)
+	case c.channelBalance:
+		event, err := BalanceEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("balance event parse error", err)
+			metrics.ParseFailures.WithLabelValues("BalanceEventFromJson").Inc()
+			return
+		}
+		// the virtual balance is kept by the paper bets in dry run
+		if !c.DryRun {
+			c.setBalance(event.Balance)
+		}
+	case c.channelNotify:
+		notifyType, notifyData, err := NotifyEventFromJson(resp.Result.Data)
+		if err != nil {
+			fmt.Println("notify event parse error", err)
+			metrics.ParseFailures.WithLabelValues("NotifyEventFromJson").Inc()
+			return
+		}
+		switch notifyType {
+		case NotifyTransfer:
+			transferEvent, _ := notifyData.(NotifyEventTransfer)
+			transferEvent.Time = c.now()
+			c.callTransferEvent(&transferEvent)
+		}
 	}
 }
 
@@ -156,6 +279,61 @@ func (c *Client) Connect() error {
 		panic(err)
 	}
 
+	if c.ScrapeRooms {
+		rooms, err := c.LoadRoomsFromSite()
+		if err != nil {
+			fmt.Println("rooms scrape error", err)
+		} else {
+			c.rooms = rooms
+		}
+	}
+
+	err = c.connectWebsocket()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if err := c.bootstrapGames(); err != nil {
+		fmt.Println("games bootstrap error", err)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Login authorizes the http session only, for tools that read the site
+// pages without listening to the websocket.
+func (c *Client) Login() error {
+	return c.vkAuthorize()
+}
+
+// reconnect dials the websocket again reusing the session cookies and
+// resyncs the running games, it retries until the connection succeeds.
+func (c *Client) reconnect() {
+	metrics.Reconnects.Inc()
+	if c.websocket != nil {
+		c.websocket.Close()
+		c.websocket = nil
+	}
+	delay := time.Second
+	for {
+		err := c.connectWebsocket()
+		if err == nil {
+			break
+		}
+		fmt.Println("reconnect error", err)
+		time.Sleep(delay)
+		if delay < time.Minute {
+			delay *= 2
+		}
+	}
+	c.mu.Lock()
+	if err := c.bootstrapGames(); err != nil {
+		fmt.Println("games bootstrap error", err)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) connectWebsocket() error {
 	info, err := c.getClientInfo()
 	if err != nil {
 		return err
@@ -196,21 +374,70 @@ func (c *Client) Connect() error {
 
 	c.websocket = conn
 	c.channelNotify = fmt.Sprintf("notify#%d", info.userId)
-	c.Balance = info.balance
+	c.channelBalance = fmt.Sprintf("balance#%d", info.userId)
+	if !c.DryRun || !c.paperStarted {
+		c.Balance = info.balance
+		if c.DryRun && c.PaperBalance > 0 {
+			c.Balance = c.PaperBalance
+		}
+		c.paperStarted = c.DryRun
+		metrics.SetBalance(c.Balance)
+	}
 	c.UserId = info.userId
 	return nil
 }
 
+// Catalogue returns the room catalogue in use.
+func (c *Client) Catalogue() Rooms {
+	return c.rooms
+}
+
 func (c *Client) MakeBet(game *Game, summ float32) error {
+	if c.replaying && !c.DryRun {
+		return fmt.Errorf("bets are disabled during replay")
+	}
+	if !game.AcceptsBets() {
+		return fmt.Errorf("game %d is %s, bets are closed", game.Id, game.State)
+	}
 	if summ > c.Balance {
 		return fmt.Errorf("not enought balance")
 	}
+	if c.Risk != nil {
+		if err := c.Risk.Check(game, summ); err != nil {
+			if riskErr, ok := err.(*RiskError); ok && c.BetRejectedHandler != nil {
+				c.BetRejectedHandler(game, riskErr)
+			}
+			return err
+		}
+	}
+	if err := c.placeBet(game, summ); err != nil {
+		metrics.BetsFailed.Inc()
+		return err
+	}
+	metrics.BetsPlaced.Inc()
+	return nil
+}
+
+// placeBet sends a bet that passed the checks, or records it on paper in
+// dry run.
+func (c *Client) placeBet(game *Game, summ float32) error {
+	if c.DryRun {
+		if err := c.paperBet(game, summ); err != nil {
+			return err
+		}
+		if c.Risk != nil {
+			c.Risk.recordBet(game, summ)
+		}
+		return nil
+	}
+	started := time.Now()
 	resp, err := c.sendPostNultipart("https://csgf.live/bet", map[string]string{
 		"gid": strconv.Itoa(game.Id),
 		"sum": fmt.Sprintf("%.2f", summ)})
 	if err != nil {
 		return err
 	}
+	c.measureBetLatency(time.Since(started))
 
 	betResp, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -228,47 +455,103 @@ func (c *Client) MakeBet(game *Game, summ float32) error {
 		return fmt.Errorf("bet failed: {%s}", data.Message.Text)
 	}
 	game.BetNow += summ
+	c.expectBet(game, summ, started)
+	if c.Risk != nil {
+		c.Risk.recordBet(game, summ)
+	}
 	return nil
 }
 
+// SendChatMessage panics when the message cannot be sent, use SendChat to
+// handle the error.
 func (c *Client) SendChatMessage(msg string) {
-	if interval := time.Now().Sub(c.lastMessageTime); interval < time.Second{
-		time.Sleep(time.Second - interval + time.Millisecond * 100)
+	if err := c.SendChat(msg); err != nil {
+		panic(err)
+	}
+}
+
+// SendChat posts a chat message. Messages of all callers share one rate
+// limit, a message waits until the previous one is messageSendInterval old.
+func (c *Client) SendChat(msg string) error {
+	if c.replaying {
+		fmt.Println("replay chat message", msg)
+		return nil
+	}
+	metrics.ChatQueued.Add(1)
+	c.chatMu.Lock()
+	defer c.chatMu.Unlock()
+	if interval := time.Since(c.lastMessageTime); interval < messageSendInterval {
+		time.Sleep(messageSendInterval - interval + 100*time.Millisecond)
 	}
+	metrics.ChatQueued.Add(-1)
 
 	resp, err := c.sendPostNultipart("https://csgf.live/chat/send", map[string]string{"message": msg})
+	c.lastMessageTime = time.Now()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer resp.Body.Close()
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chat message failed: %s", resp.Status)
+	}
+	metrics.ChatSent.Inc()
 	fmt.Println(string(data))
+	return nil
 }
 
 func (c *Client) SendTransfer(userId int, summ float32) error {
+	if c.replaying {
+		fmt.Println("replay transfer", userId, summ)
+		return nil
+	}
+	if err := c.sendTransfer(userId, summ); err != nil {
+		metrics.TransfersFailed.Inc()
+		return err
+	}
+	metrics.TransfersSent.Inc()
+	return nil
+}
+
+// TransferRejectedError is returned when the site answered that it did not
+// make the transfer. After any other transfer error it is unknown whether
+// the money was sent.
+type TransferRejectedError struct {
+	Text string
+}
+
+func (e *TransferRejectedError) Error() string {
+	return fmt.Sprintf("transfer failed: {%s}", e.Text)
+}
+
+func (c *Client) sendTransfer(userId int, summ float32) error {
 	resp, err := c.sendPostNultipart("https://csgf.live/transfer", map[string]string{"id": strconv.Itoa(userId), "sum": fmt.Sprintf("%.2f", summ)})
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer resp.Body.Close()
 	text := new(strings.Builder)
 	io.Copy(text, resp.Body)
 	fmt.Println("transfer response", text.String())
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("transfer failed: %s", resp.Status)
+	}
+	// the site answers with the same message format as for bets
+	var data csgfBetResponse
+	if json.Unmarshal([]byte(text.String()), &data) == nil && data.Message.Status != "" && data.Message.Status != "success" {
+		return &TransferRejectedError{Text: data.Message.Text}
+	}
 	return nil
 }
 
 func (c *Client) getClientInfo() (*clientInfo, error) {
-	res, err := c.httpClient.Get("https://csgf.live/")
-
-	if err != nil {
-		return nil, err
-	}
-	data, err := io.ReadAll(res.Body)
+	html, err := c.getPage("https://csgf.live/")
 	if err != nil {
 		return nil, err
 	}
-	html := string(data)
 	tokenRegexp := regexp.MustCompile(`TOKEN = "(?P<token>.+?)"`)
 	token := tokenRegexp.FindStringSubmatch(html)
 	if token == nil {
@@ -346,42 +629,120 @@ func (c *Client) vkAuthorize() error {
 }
 
 func (c *Client) processNewGameEvent(event *NewGameEvent) {
-	game, err := NewGame(event.GameId, event.Room)
+	if _, ok := c.openedGames[event.GameId]; ok {
+		fmt.Println("new_game for already opened game", event.GameId)
+		return
+	}
+	game, err := NewGame(event.GameId, event.Room, c.rooms)
 	if err != nil {
-		panic(err)
+		fmt.Println("new game error", err)
+		return
 	}
+	game.Created = c.now()
+	game.clock = c.now
 	c.openedGames[game.Id] = game
 	c.callGameUpdate(game, GameNew)
+	c.flushEvents(game.Id)
 }
 
 func (c *Client) processTimeEvent(event *TimeEvent) {
-	if game, ok := c.openedGames[event.GameId]; ok {
-		game.TimeNow = event.Time
-		c.callGameUpdate(game, GameTime)
-	}
+	game, ok := c.openedGames[event.GameId]
+	if !ok {
+		c.bufferEvent(event.GameId, event)
+		return
+	}
+	state := GameStateCountdown
+	if event.Time <= 0 {
+		state = GameStateDrawing
+	}
+	if err := game.setState(state); err != nil {
+		fmt.Println("time event rejected", err)
+		return
+	}
+	game.syncTimer(event.Time)
+	if game.AcceptsBets() {
+		c.reschedule(game)
+	} else {
+		c.cancelScheduled(game.Id)
+	}
+	c.callGameUpdate(game, GameTime)
 }
 
 func (c *Client) processNewBetEvent(event *NewBetEvent) {
-	if game, ok := c.openedGames[event.GameId]; ok {
-
-		game.Bank = event.CurrentBank
-		if event.UserId != c.UserId {
-			c.callGameUpdate(game, GameBet)
-		}
+	if now := c.now(); event.Time.After(now) {
+		event.Time = now
+	}
+	game, ok := c.openedGames[event.GameId]
+	if !ok {
+		c.bufferEvent(event.GameId, event)
+		return
+	}
+	state := GameStateBetting
+	if game.State == GameStateCountdown {
+		state = GameStateCountdown
+	}
+	if err := game.setState(state); err != nil {
+		fmt.Println("bet event rejected", err)
+		return
+	}
+
+	game.Bank = event.CurrentBank + game.VirtualStake
+	game.AddBet(Bet{
+		UserId:     event.UserId,
+		Username:   event.Username,
+		Amount:     event.Summ,
+		Time:       event.Time,
+		TicketFrom: event.TicketFrom,
+		TicketTo:   event.TicketTo,
+	})
+	if event.UserId == c.UserId {
+		c.confirmBet(game, event)
+	} else {
+		c.callGameUpdate(game, GameBet)
 	}
 }
 
 func (c *Client) processEndGameEvent(event *EndGameEvent) {
-	if game, ok := c.openedGames[event.GameId]; ok {
-		delete(c.openedGames, event.GameId)
-		c.callGameUpdate(game, GameEnd)
-	}
+	game, ok := c.openedGames[event.GameId]
+	if !ok {
+		c.bufferEvent(event.GameId, event)
+		return
+	}
+	// the game is over on the site anyway, so a bad transition is only reported
+	if err := game.setState(GameStateFinished); err != nil {
+		fmt.Println("end game event", err)
+		game.State = GameStateFinished
+	}
+	delete(c.openedGames, event.GameId)
+	c.cancelScheduled(event.GameId)
+	c.dropPendingBets(game)
+	game.Result = event
+	game.Finished = c.now()
+	game.Won = event.WinnerId != 0 && event.WinnerId == c.UserId
+	if event.Bank > 0 {
+		game.Bank = event.Bank + game.VirtualStake
+	}
+	if c.DryRun {
+		c.settlePaper(game)
+	}
+	c.settle(game)
+	c.callGameUpdate(game, GameEnd)
 }
 
-func (c *Client) callGameUpdate(game *Game, reason GameUpdateReason) {
-	if c.GameUpdateHandler != nil {
-		c.GameUpdateHandler(game, reason)
+func (c *Client) getPage(url string) (string, error) {
+	res, err := c.httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", url, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
 }
 
 func (c *Client) sendPostNultipart(url string, data map[string]string) (*http.Response, error) {
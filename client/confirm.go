@@ -0,0 +1,97 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const defaultBetConfirmTimeout = 5 * time.Second
+
+// BetConfirmation reports whether our bet showed up in the new_bet stream.
+type BetConfirmation struct {
+	Game      *Game
+	Amount    float32
+	Sent      time.Time
+	Confirmed bool
+	// Latency is the time from sending the bet to its echo
+	Latency time.Duration
+}
+
+type pendingBet struct {
+	amount float32
+	sent   time.Time
+	timer  *time.Timer
+}
+
+// expectBet waits for the echo of a bet accepted by the site.
+func (c *Client) expectBet(game *Game, amount float32, sent time.Time) {
+	pending := &pendingBet{amount: amount, sent: sent}
+	timeout := c.BetConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultBetConfirmTimeout
+	}
+	pending.timer = time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.removePendingBet(game.Id, pending) {
+			fmt.Printf("bet %.2f in game %d not confirmed after %s\n", amount, game.Id, timeout)
+			c.callBetConfirmation(&BetConfirmation{Game: game, Amount: amount, Sent: sent})
+		}
+	})
+	c.pendingBets[game.Id] = append(c.pendingBets[game.Id], pending)
+}
+
+// confirmBet matches our bet from the stream with the oldest pending bet of
+// the same amount and reconciles BetNow with the site numbers.
+func (c *Client) confirmBet(game *Game, event *NewBetEvent) {
+	for _, pending := range c.pendingBets[game.Id] {
+		if math.Abs(float64(pending.amount-event.Summ)) < 0.005 {
+			pending.timer.Stop()
+			c.removePendingBet(game.Id, pending)
+			c.callBetConfirmation(&BetConfirmation{
+				Game:      game,
+				Amount:    event.Summ,
+				Sent:      pending.sent,
+				Confirmed: true,
+				Latency:   event.Time.Sub(pending.sent),
+			})
+			break
+		}
+	}
+	c.reconcileBetNow(game)
+}
+
+func (c *Client) reconcileBetNow(game *Game) {
+	betNow := game.UserTotal(c.UserId)
+	for _, pending := range c.pendingBets[game.Id] {
+		betNow += pending.amount
+	}
+	if math.Abs(float64(betNow-game.BetNow)) >= 0.005 {
+		fmt.Printf("game %d stake reconciled %.2f -> %.2f\n", game.Id, game.BetNow, betNow)
+	}
+	game.BetNow = betNow
+}
+
+func (c *Client) removePendingBet(gameId int, pending *pendingBet) bool {
+	bets := c.pendingBets[gameId]
+	for i, bet := range bets {
+		if bet == pending {
+			c.pendingBets[gameId] = append(bets[:i], bets[i+1:]...)
+			if len(c.pendingBets[gameId]) == 0 {
+				delete(c.pendingBets, gameId)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// dropPendingBets flags the bets of a closed game that were never confirmed.
+func (c *Client) dropPendingBets(game *Game) {
+	for _, pending := range c.pendingBets[game.Id] {
+		pending.timer.Stop()
+		c.callBetConfirmation(&BetConfirmation{Game: game, Amount: pending.amount, Sent: pending.sent})
+	}
+	delete(c.pendingBets, game.Id)
+}
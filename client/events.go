@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 type NewGameEvent struct {
@@ -17,12 +18,20 @@ func NewGameEventFromJson(data map[string]interface{}) (*NewGameEvent, error) {
 		return nil, fmt.Errorf("data filed not found")
 	}
 
-	room, err := strconv.Atoi(eventData["room"].(string))
-	if err != nil {
+	room, ok := jsonInt(eventData["room"])
+	if !ok {
 		return nil, fmt.Errorf("failed to parse room field")
 	}
 
-	gameId, err := strconv.Atoi(regexp.MustCompile(`game_(\d+)`).FindStringSubmatch(eventData["blade"].(string))[1])
+	html, ok := eventData["blade"].(string)
+	if !ok {
+		return nil, fmt.Errorf("blade field not found")
+	}
+	gameData := regexp.MustCompile(`game_(\d+)`).FindStringSubmatch(html)
+	if gameData == nil {
+		return nil, fmt.Errorf("cannot find game id")
+	}
+	gameId, err := strconv.Atoi(gameData[1])
 	if err != nil {
 		return nil, err
 	}
@@ -33,15 +42,60 @@ func NewGameEventFromJson(data map[string]interface{}) (*NewGameEvent, error) {
 }
 
 type EndGameEvent struct {
-	GameId int
+	GameId     int
+	WinnerId   int
+	WinnerName string
+	Ticket     int
+	Bank       float32
+	// Commission is the amount kept by the site, zero when not reported
+	Commission float32
+	Hash       string
+	Seed       string
 }
 
 func EndGameEventFromJson(data map[string]interface{}) (*EndGameEvent, error) {
-	gameId, err := strconv.Atoi((data["data"]).(map[string]interface{})["game"].(string))
-	if err != nil {
+	eventData, ok := data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("data field not found")
+	}
+	gameId, ok := jsonInt(eventData["game"])
+	if !ok {
 		return nil, fmt.Errorf("cannot parse gameId")
 	}
-	return &EndGameEvent{gameId}, nil
+	event := &EndGameEvent{GameId: gameId}
+
+	// everything except the game id is optional, the payload differs between rooms
+	winner := eventData
+	if winnerData, ok := eventData["winner"].(map[string]interface{}); ok {
+		winner = winnerData
+		event.WinnerId, _ = jsonInt(firstField(winner, "id", "user_id"))
+	} else {
+		event.WinnerId, _ = jsonInt(firstField(eventData, "winner", "winner_id", "user_id"))
+	}
+	event.WinnerName, _ = firstField(winner, "username", "name", "winner_name").(string)
+	event.Ticket, _ = jsonInt(firstField(eventData, "ticket", "winner_ticket", "number"))
+	if bank, ok := jsonFloat(firstField(eventData, "bank", "price")); ok {
+		event.Bank = float32(bank)
+	}
+	if commission, ok := jsonFloat(firstField(eventData, "commission", "comission")); ok {
+		event.Commission = float32(commission)
+	}
+	event.Hash, _ = firstField(eventData, "hash").(string)
+	event.Seed, _ = firstField(eventData, "seed", "secret", "random").(string)
+
+	if html, ok := eventData["blade"].(string); ok {
+		if event.WinnerId == 0 {
+			if userData := regexp.MustCompile(`<a href="\/user\/(\d+)"`).FindStringSubmatch(html); userData != nil {
+				event.WinnerId, _ = strconv.Atoi(userData[1])
+			}
+		}
+		if event.Ticket == 0 {
+			if ticketData := regexp.MustCompile(`[Бб]илет[^\d]*#?(\d+)`).FindStringSubmatch(html); ticketData != nil {
+				event.Ticket, _ = strconv.Atoi(ticketData[1])
+			}
+		}
+	}
+	return event, nil
 }
 
 type NewBetEvent struct {
@@ -49,6 +103,10 @@ type NewBetEvent struct {
 	GameId      int
 	UserId      int
 	Summ 		float32
+	Username    string
+	TicketFrom  int
+	TicketTo    int
+	Time        time.Time
 }
 
 func NewBetEventFromJson(data map[string]interface{}) (*NewBetEvent, error) {
@@ -56,15 +114,18 @@ func NewBetEventFromJson(data map[string]interface{}) (*NewBetEvent, error) {
 	if !ok {
 		return nil, fmt.Errorf("event data not found")
 	}
-	currentBank, err := strconv.ParseFloat(eventData["bank"].(string), 32)
-	if err != nil {
-		return nil, fmt.Errorf("cannot convert bank field (%s) to float", eventData["bank"].(string))
+	currentBank, ok := jsonFloat(eventData["bank"])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert bank field (%v) to float", eventData["bank"])
 	}
-	gameId, err := strconv.Atoi(eventData["game"].(string))
-	if err != nil {
-		return nil, fmt.Errorf("cannot parse gameId %s", eventData["game"].(string))
+	gameId, ok := jsonInt(eventData["game"])
+	if !ok {
+		return nil, fmt.Errorf("cannot parse gameId %v", eventData["game"])
+	}
+	html, ok := eventData["blade"].(string)
+	if !ok {
+		return nil, fmt.Errorf("blade field not found")
 	}
-	html := eventData["blade"].(string)
 	
 	usersRegex := regexp.MustCompile(`<a href="\/user\/(\d+)">`)
 	usersData := usersRegex.FindStringSubmatch(html)
@@ -84,12 +145,23 @@ func NewBetEventFromJson(data map[string]interface{}) (*NewBetEvent, error) {
 	if err != nil{
 		return nil, fmt.Errorf("cannot parse bet")
 	}
-	return &NewBetEvent{
+	event := &NewBetEvent{
 		CurrentBank: float32(currentBank),
 		GameId:      gameId,
 		UserId:      userId,
 		Summ: float32(bet),
-	}, nil
+		Time:        time.Now(),
+	}
+
+	// username and tickets are optional, older markup does not contain them
+	if usernameData := regexp.MustCompile(`class="name"[^>]*>(.+?)<`).FindStringSubmatch(html); usernameData != nil {
+		event.Username = usernameData[1]
+	}
+	if ticketsData := regexp.MustCompile(`#(\d+)\s*-\s*#(\d+)`).FindStringSubmatch(html); ticketsData != nil {
+		event.TicketFrom, _ = strconv.Atoi(ticketsData[1])
+		event.TicketTo, _ = strconv.Atoi(ticketsData[2])
+	}
+	return event, nil
 }
 
 type BalanceEvent struct {
@@ -97,8 +169,12 @@ type BalanceEvent struct {
 }
 
 func BalanceEventFromJson(data map[string]interface{}) (*BalanceEvent, error) {
-	if balance, ok := data["data"].(map[string]interface{})["balance"].(float32); ok {
-		return &BalanceEvent{Balance: balance}, nil
+	eventData, ok := data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("data field not found")
+	}
+	if balance, ok := jsonFloat(eventData["balance"]); ok {
+		return &BalanceEvent{Balance: float32(balance)}, nil
 	}
 	return nil, fmt.Errorf("balance field not found")
 }
@@ -114,20 +190,20 @@ func TimeEventFromJson(data map[string]interface{}) (*TimeEvent, error) {
 	if !ok {
 		return nil, fmt.Errorf("data field not found")
 	}
-	gameId, ok := eventData["game"].(int)
+	gameId, ok := jsonInt(eventData["game"])
 	if !ok {
 		return nil, fmt.Errorf("game field not found")
 	}
-	room, ok := eventData["room"].(int)
+	room, ok := jsonInt(eventData["room"])
 	if !ok {
 		return nil, fmt.Errorf("room field not found")
 	}
-	time, ok := eventData["time"].(int)
+	timeLeft, ok := jsonInt(eventData["time"])
 	if !ok {
 		return nil, fmt.Errorf("time field not found")
 	}
 	return &TimeEvent{
-		Time:   time,
+		Time:   timeLeft,
 		Room:   room,
 		GameId: gameId,
 	}, nil
@@ -137,10 +213,12 @@ type ChatEvent struct{
 	Message string
 	UserId int
 	Username string
+	Time time.Time
 }
 
 func ChatEventFromJson(data map[string]interface{}) (*ChatEvent, error) {
-	html, ok := data["data"].(map[string]interface{})["blade"].(string)
+	eventData, _ := data["data"].(map[string]interface{})
+	html, ok := eventData["blade"].(string)
 	if !ok {
 		return nil, fmt.Errorf("blade field not found")
 	}
@@ -175,10 +253,13 @@ const (
 type NotifyEventTransfer struct{
 	Amount float32
 	FromUser string
+	Time time.Time
 }
 
 func NotifyEventFromJson(data map[string]interface{}) (NotifyEventType, interface{}, error) {
-	eventText, ok := data["data"].(map[string]interface{})["message"].(map[string]interface{})["text"].(string)
+	eventData, _ := data["data"].(map[string]interface{})
+	message, _ := eventData["message"].(map[string]interface{})
+	eventText, ok := message["text"].(string)
 	if !ok{
 		return NotifyUnknown, nil, fmt.Errorf("cannot find event text")
 	}
@@ -187,8 +268,41 @@ func NotifyEventFromJson(data map[string]interface{}) (NotifyEventType, interfac
 	transferData := transferRegexp.FindStringSubmatch(eventText)
 	if transferData != nil{
 		amount, _ := strconv.ParseFloat(transferData[1], 32)
-		return NotifyTransfer, NotifyEventTransfer{float32(amount), transferData[2]}, nil
+		return NotifyTransfer, NotifyEventTransfer{Amount: float32(amount), FromUser: transferData[2]}, nil
 	}
 
 	return NotifyUnknown, nil, nil
-}
\ No newline at end of file
+}
+
+// firstField returns the first non nil value among the given keys.
+func firstField(data map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if value, ok := data[key]; ok && value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// jsonFloat accepts both json numbers and numeric strings, the site mixes them.
+func jsonFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func jsonInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case string:
+		i, err := strconv.Atoi(v)
+		return i, err == nil
+	}
+	return 0, false
+}
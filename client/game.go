@@ -2,6 +2,7 @@ package client
 
 import (
 	"math"
+	"time"
 )
 
 type Game struct {
@@ -14,63 +15,93 @@ type Game struct {
 	MaxBet  float32
 	BetNow  float32
 	TimeNow int
+	State   GameState
+	// Commission is the share of the bank kept by the site
+	Commission float32
+	// VirtualStake is our paper trading stake, it is included in Bank
+	VirtualStake float32
+
+	// Bets in the order they arrived, Totals is the sum of bets by user id
+	Bets   []Bet
+	Totals map[int]float32
+
+	// Result is set when the game ends, Won tells if the winner is us
+	Result *EndGameEvent
+	Won    bool
+
+	Created  time.Time
+	Finished time.Time
+
+	deadline time.Time
+	// clock is the time source of the countdown, the replay time in replay
+	clock func() time.Time
 }
 
-var MaxBankLimits = map[int]float32{
-	1: 500,
-	2: 50,
-	3: 100,
-	4: 2500,
-	5: 10000,
-	6: 50000,
+type Bet struct {
+	UserId     int
+	Username   string
+	Amount     float32
+	Time       time.Time
+	TicketFrom int
+	TicketTo   int
+	// Virtual bets were only recorded in paper trading mode
+	Virtual bool
 }
 
-var MaxBetLimits = map[int]float32{
-	1: 50,
-	2: 5,
-	3: 50,
-	4: 250,
-	5: 1000,
-	6: 5000,
+func NewGame(id int, roomId int, rooms Rooms) (*Game, error) {
+	room, err := rooms.Get(roomId)
+	if err != nil {
+		return nil, err
+	}
+	return &Game{
+		Id:         id,
+		Room:       room.Name,
+		RoomId:     roomId,
+		MaxBank:    room.MaxBank,
+		MinBet:     room.MinBet,
+		MaxBet:     room.MaxBet,
+		Commission: room.Commission,
+		TimeNow:    room.Time,
+		Created:    time.Now(),
+		Bets:       []Bet{},
+		Totals:     map[int]float32{},
+		clock:      time.Now,
+	}, nil
 }
 
-var MinBetLimits = map[int]float32{
-	1: 1,
-	2: 0.1,
-	3: 10,
-	4: 10,
-	5: 50,
-	6: 250,
+func (g *Game) AddBet(bet Bet) {
+	g.Bets = append(g.Bets, bet)
+	g.Totals[bet.UserId] += bet.Amount
 }
 
-var RoomNames = map[int]string{
-	1: "classic",
-	2: "bich",
-	3: "dual",
-	4: "rich",
-	5: "king",
-	6: "epic",
+// LastBet returns the most recent bet or nil when the game has no bets yet.
+func (g *Game) LastBet() *Bet {
+	if len(g.Bets) == 0 {
+		return nil
+	}
+	return &g.Bets[len(g.Bets)-1]
 }
 
-var RoomTimes = map[int]int {
-	1:15,
-	2:10,
-	3:1,
-	4:20,
-	5:25,
-	6:30,
+func (g *Game) Players() int {
+	return len(g.Totals)
 }
 
-func NewGame(id int, roomId int) (*Game, error) {
-	return &Game{
-		Id:      id,
-		Room:    RoomNames[roomId],
-		RoomId:  roomId,
-		MaxBank: MaxBankLimits[roomId],
-		MinBet:  MinBetLimits[roomId],
-		MaxBet:  MaxBetLimits[roomId],
-		TimeNow: RoomTimes[roomId],
-	}, nil
+func (g *Game) UserTotal(userId int) float32 {
+	return g.Totals[userId]
+}
+
+// UserChance returns the share of the bank that belongs to the user.
+func (g *Game) UserChance(userId int) float32 {
+	bank := g.Bank
+	if bank <= 0 {
+		for _, total := range g.Totals {
+			bank += total
+		}
+	}
+	if bank <= 0 {
+		return 0
+	}
+	return g.Totals[userId] / bank
 }
 
 func (g *Game) GetCurrentPercent() float32 {
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/metrics"
+)
+
+// The Add*Handler methods register handlers next to the ones in
+// ClientConfig, so several plugins can follow the same events.
+
+func (c *Client) AddGameUpdateHandler(handler GameUpdateHandler) {
+	c.gameUpdateHandlers = append(c.gameUpdateHandlers, handler)
+}
+
+func (c *Client) AddSettlementHandler(handler SettlementHandler) {
+	c.settlementHandlers = append(c.settlementHandlers, handler)
+}
+
+func (c *Client) AddBetConfirmHandler(handler BetConfirmHandler) {
+	c.betConfirmHandlers = append(c.betConfirmHandlers, handler)
+}
+
+func (c *Client) AddBalanceUpdateHandler(handler BalanceUpdateHandler) {
+	c.balanceHandlers = append(c.balanceHandlers, handler)
+}
+
+func (c *Client) AddChatUpdateHandler(handler ChatUpdateHandler) {
+	c.chatHandlers = append(c.chatHandlers, handler)
+}
+
+func (c *Client) AddTransferEventHandler(handler TransferEventHandler) {
+	c.transferHandlers = append(c.transferHandlers, handler)
+}
+
+func (c *Client) AddDisconnectHandler(handler DisconnectHandler) {
+	c.disconnectHandlers = append(c.disconnectHandlers, handler)
+}
+
+func (c *Client) callGameUpdate(game *Game, reason GameUpdateReason) {
+	if c.GameUpdateHandler != nil {
+		c.GameUpdateHandler(game, reason)
+	}
+	for _, handler := range c.gameUpdateHandlers {
+		handler(game, reason)
+	}
+}
+
+func (c *Client) callSettlement(settlement *Settlement) {
+	if c.SettlementHandler != nil {
+		c.SettlementHandler(settlement)
+	}
+	for _, handler := range c.settlementHandlers {
+		handler(settlement)
+	}
+}
+
+func (c *Client) callBetConfirmation(confirmation *BetConfirmation) {
+	if c.BetConfirmHandler != nil {
+		c.BetConfirmHandler(confirmation)
+	}
+	for _, handler := range c.betConfirmHandlers {
+		handler(confirmation)
+	}
+}
+
+func (c *Client) callChatUpdate(event *ChatEvent) {
+	if c.ChatUpdateHandler != nil {
+		c.ChatUpdateHandler(event)
+	}
+	for _, handler := range c.chatHandlers {
+		handler(event)
+	}
+}
+
+func (c *Client) callTransferEvent(event *NotifyEventTransfer) {
+	if c.TransferEventHandler != nil {
+		c.TransferEventHandler(event)
+	}
+	for _, handler := range c.transferHandlers {
+		handler(event)
+	}
+}
+
+func (c *Client) callDisconnect(err error) {
+	if c.DisconnectHandler != nil {
+		c.DisconnectHandler(err)
+	}
+	for _, handler := range c.disconnectHandlers {
+		handler(err)
+	}
+}
+
+func (c *Client) setBalance(balance float32) {
+	c.Balance = balance
+	metrics.SetBalance(balance)
+	if c.BalanceUpdateHandler != nil {
+		c.BalanceUpdateHandler(BalanceEvent{Balance: balance})
+	}
+	for _, handler := range c.balanceHandlers {
+		handler(BalanceEvent{Balance: balance})
+	}
+}
+
+// now is the time of the event being processed, during replay it is the
+// time of the recorded frame.
+func (c *Client) now() time.Time {
+	if c.replaying {
+		return c.replayTime
+	}
+	return time.Now()
+}
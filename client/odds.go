@@ -0,0 +1,63 @@
+package client
+
+// WinChance is our probability to win the game with the current bank.
+func (g *Game) WinChance() float32 {
+	return g.chanceWith(0)
+}
+
+// WinAmount is what the winner receives, the bank minus the site commission.
+func (g *Game) WinAmount() float32 {
+	if g.Result != nil && g.Result.Commission > 0 {
+		return g.Bank - g.Result.Commission
+	}
+	return g.Bank * (1 - g.Commission)
+}
+
+func (g *Game) chanceWith(stake float32) float32 {
+	bank := g.Bank + stake
+	if bank <= 0 {
+		return 0
+	}
+	return (g.BetNow + stake) / bank
+}
+
+// ExpectedValue is the change of our expected balance when we add stake to
+// the game, the winner gets the bank minus the room commission.
+func (g *Game) ExpectedValue(stake float32) float32 {
+	before := g.chanceWith(0)*g.Bank*(1-g.Commission) - g.BetNow
+	bank := g.Bank + stake
+	after := g.chanceWith(stake)*bank*(1-g.Commission) - (g.BetNow + stake)
+	return after - before
+}
+
+type EVPoint struct {
+	Stake  float32
+	Chance float32
+	EV     float32
+	// Marginal is the EV of the last step alone
+	Marginal float32
+}
+
+// EVCurve evaluates ExpectedValue for stakes from step up to max, the
+// stakes are limited by MaxBank.
+func (g *Game) EVCurve(step float32, max float32) []EVPoint {
+	points := []EVPoint{}
+	if step <= 0 {
+		return points
+	}
+	if limit := g.MaxBank - g.Bank; max > limit {
+		max = limit
+	}
+	var previous float32
+	for stake := step; stake <= max+1e-4; stake += step {
+		ev := g.ExpectedValue(stake)
+		points = append(points, EVPoint{
+			Stake:    stake,
+			Chance:   g.chanceWith(stake),
+			EV:       ev,
+			Marginal: ev - previous,
+		})
+		previous = ev
+	}
+	return points
+}
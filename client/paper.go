@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// paperBet records a virtual bet against the virtual balance.
+func (c *Client) paperBet(game *Game, summ float32) error {
+	if err := game.AddVirtualBet(c.UserId, summ, c.now()); err != nil {
+		return err
+	}
+	fmt.Printf("paper bet %.2f in game %d\n", summ, game.Id)
+	c.setBalance(c.Balance - summ)
+	return nil
+}
+
+// AddVirtualBet adds a bet that is not part of the real pot, the stake is
+// added to the bank so chances are computed against the pot as it would be
+// with our bet in it.
+func (g *Game) AddVirtualBet(userId int, summ float32, placed time.Time) error {
+	if summ < g.MinBet || summ > g.MaxBet {
+		return fmt.Errorf("bet failed: {bet %.2f outside of %.2f-%.2f}", summ, g.MinBet, g.MaxBet)
+	}
+	if g.Bank+summ > g.MaxBank {
+		return fmt.Errorf("bet failed: {bank limit %.2f reached}", g.MaxBank)
+	}
+	g.BetNow += summ
+	g.VirtualStake += summ
+	g.Bank += summ
+	g.AddBet(Bet{UserId: userId, Amount: summ, Time: placed, Virtual: true})
+	return nil
+}
+
+// VirtualWin decides whether a virtual stake that was not part of the real
+// pot would have won. The virtual tickets are appended after the real ones
+// and the winning ticket is scaled to the enlarged ticket range; without a
+// recorded ticket the outcome is drawn with the stake share as probability.
+// game.Bank must include the virtual stake.
+func VirtualWin(game *Game, stake float32) bool {
+	realBank := game.Bank - stake
+	if stake <= 0 {
+		return false
+	}
+	if realBank <= 0 {
+		return true
+	}
+
+	// ticket ranges are only trusted when every real bet has one
+	realTickets := 0
+	for _, bet := range game.Bets {
+		if bet.Virtual {
+			continue
+		}
+		if bet.TicketTo == 0 {
+			realTickets = 0
+			break
+		}
+		if bet.TicketTo > realTickets {
+			realTickets = bet.TicketTo
+		}
+	}
+	if realTickets == 0 {
+		realTickets = int(realBank * 100)
+	}
+	if game.Result != nil && game.Result.Ticket > 0 && game.Result.Ticket <= realTickets {
+		position := float64(game.Result.Ticket-1) / float64(realTickets)
+		virtualTickets := float64(realTickets) * float64(stake/realBank)
+		return position*(float64(realTickets)+virtualTickets) >= float64(realTickets)
+	}
+	return rand.Float32() < stake/game.Bank
+}
+
+// settlePaper replaces the real winner with the virtual outcome.
+func (c *Client) settlePaper(game *Game) {
+	if game.VirtualStake <= 0 {
+		return
+	}
+	game.Won = VirtualWin(game, game.VirtualStake)
+	if game.Won {
+		c.setBalance(c.Balance + game.WinAmount())
+	}
+}
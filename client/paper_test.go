@@ -0,0 +1,43 @@
+package client
+
+import "testing"
+
+func TestVirtualWin(t *testing.T) {
+	// two real bets of 10 with tickets 1-1000 and 1001-2000
+	ticketed := []Bet{{UserId: 1, Amount: 10, TicketFrom: 1, TicketTo: 1000}, {UserId: 2, Amount: 10, TicketFrom: 1001, TicketTo: 2000}}
+	untracked := []Bet{{UserId: 1, Amount: 10}, {UserId: 2, Amount: 10}}
+	tests := []struct {
+		name   string
+		bets   []Bet
+		stake  float32
+		ticket int
+		want   bool
+	}{
+		{name: "no stake", bets: ticketed, stake: 0, ticket: 1, want: false},
+		{name: "only our stake", stake: 5, want: true},
+		{name: "ticket in the virtual range", bets: ticketed, stake: 20, ticket: 1500, want: true},
+		{name: "ticket in the real range", bets: ticketed, stake: 20, ticket: 500, want: false},
+		{name: "small stake wins the last tickets", bets: ticketed, stake: 5, ticket: 1900, want: true},
+		{name: "small stake loses", bets: ticketed, stake: 5, ticket: 1500, want: false},
+		{name: "tickets from the bank", bets: untracked, stake: 20, ticket: 1999, want: true},
+		{name: "tickets from the bank lose", bets: untracked, stake: 20, ticket: 2, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := &Game{Bank: test.stake, Totals: map[int]float32{}}
+			for _, bet := range test.bets {
+				game.AddBet(bet)
+				game.Bank += bet.Amount
+			}
+			if test.stake > 0 {
+				game.AddBet(Bet{UserId: 3, Amount: test.stake, Virtual: true})
+			}
+			if test.ticket > 0 {
+				game.Result = &EndGameEvent{Ticket: test.ticket}
+			}
+			if got := VirtualWin(game, test.stake); got != test.want {
+				t.Errorf("VirtualWin %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"math"
+)
+
+// BetPlan is a sequence of legal bets towards a target chance. Reason tells
+// why the target could not be reached and is empty when it is.
+type BetPlan struct {
+	Bets   []float32
+	Total  float32
+	Chance float32
+	Reason string
+}
+
+func toCents(amount float32) int {
+	return int(math.Round(float64(amount) * 100))
+}
+
+func fromCents(cents int) float32 {
+	return float32(cents) / 100
+}
+
+// PlanBets splits the stake needed to own target share of the bank into bets
+// within MinBet and MaxBet, keeping the bank within MaxBank and the total
+// within balance. When the target is out of reach the plan gets as close as
+// the limits allow.
+func (g *Game) PlanBets(target float32, balance float32) *BetPlan {
+	plan := &BetPlan{Bets: []float32{}, Chance: g.GetCurrentPercent()}
+	if g.Bank <= 0 {
+		plan.Chance = 0
+	}
+	if target <= 0 || target >= 1 {
+		plan.Reason = "target must be between 0 and 1"
+		return plan
+	}
+	if plan.Chance >= target {
+		return plan
+	}
+
+	minBet, maxBet := toCents(g.MinBet), toCents(g.MaxBet)
+	need := int(math.Ceil(float64((target*g.Bank-g.BetNow)/(1-target)*100) - 1e-6))
+	available := toCents(g.MaxBank) - toCents(g.Bank)
+	limit := "bank limit reached"
+	if cents := toCents(balance); cents < available {
+		available = cents
+		limit = "not enough balance"
+	}
+
+	stake := need
+	if stake < minBet {
+		stake = minBet
+	}
+	if stake > available {
+		stake = available
+		plan.Reason = limit
+	}
+	if stake < minBet {
+		plan.Reason = limit + " before the minimum bet"
+		return plan
+	}
+
+	bets := []int{}
+	for left := stake; left > 0; {
+		bet := left
+		if bet > maxBet {
+			bet = maxBet
+		}
+		bets = append(bets, bet)
+		left -= bet
+	}
+	// a tail below the minimum bet borrows from the previous bet or is dropped
+	if last := len(bets) - 1; bets[last] < minBet {
+		missing := minBet - bets[last]
+		if last > 0 && bets[last-1]-missing >= minBet {
+			bets[last-1] -= missing
+			bets[last] = minBet
+		} else {
+			stake -= bets[last]
+			bets = bets[:last]
+			plan.Reason = "remainder below the minimum bet"
+		}
+	}
+
+	for _, bet := range bets {
+		plan.Bets = append(plan.Bets, fromCents(bet))
+	}
+	plan.Total = fromCents(stake)
+	plan.Chance = (g.BetNow + plan.Total) / (g.Bank + plan.Total)
+	return plan
+}
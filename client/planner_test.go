@@ -0,0 +1,61 @@
+package client
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPlanBets(t *testing.T) {
+	tests := []struct {
+		name    string
+		room    Room
+		bank    float32
+		betNow  float32
+		target  float32
+		balance float32
+		bets    []float32
+		reason  string
+	}{
+		{name: "split by max bet", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 100, target: 0.5, balance: 1000, bets: []float32{50, 50}},
+		{name: "raised to min bet", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 100, target: 0.001, balance: 1000, bets: []float32{1}},
+		{name: "target reached", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 100, betNow: 60, target: 0.5, balance: 1000, bets: []float32{}},
+		{name: "invalid target", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 100, target: 1, balance: 1000, bets: []float32{}, reason: "target must be between 0 and 1"},
+		{name: "balance limit", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 100, target: 0.5, balance: 30, bets: []float32{30}, reason: "not enough balance"},
+		{name: "bank limit", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 480, target: 0.5, balance: 1000, bets: []float32{20}, reason: "bank limit reached"},
+		{name: "bank limit below min bet", room: Room{MaxBank: 500, MinBet: 1, MaxBet: 50}, bank: 499.5, target: 0.5, balance: 1000, bets: []float32{}, reason: "bank limit reached before the minimum bet"},
+		{name: "tail borrows", room: Room{MaxBank: 1000, MinBet: 10, MaxBet: 50}, bank: 105, target: 0.5, balance: 1000, bets: []float32{50, 45, 10}},
+		{name: "tail dropped", room: Room{MaxBank: 1000, MinBet: 10, MaxBet: 10}, bank: 18, target: 0.5, balance: 1000, bets: []float32{10}, reason: "remainder below the minimum bet"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := &Game{
+				Bank:    test.bank,
+				BetNow:  test.betNow,
+				MaxBank: test.room.MaxBank,
+				MinBet:  test.room.MinBet,
+				MaxBet:  test.room.MaxBet,
+			}
+			plan := game.PlanBets(test.target, test.balance)
+			if !reflect.DeepEqual(plan.Bets, test.bets) {
+				t.Errorf("bets %v, want %v", plan.Bets, test.bets)
+			}
+			if plan.Reason != test.reason {
+				t.Errorf("reason %q, want %q", plan.Reason, test.reason)
+			}
+			var total float32
+			for _, bet := range test.bets {
+				total += bet
+			}
+			if plan.Total != total {
+				t.Errorf("total %.2f, want %.2f", plan.Total, total)
+			}
+			if len(test.bets) > 0 {
+				chance := (test.betNow + total) / (test.bank + total)
+				if math.Abs(float64(plan.Chance-chance)) > 1e-6 {
+					t.Errorf("chance %.4f, want %.4f", plan.Chance, chance)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxRecordedFrameSize limits a single line of a recording; chat and bet
+// frames carry rendered html so they can be quite large.
+const maxRecordedFrameSize = 16 * 1024 * 1024
+
+type RecordedFrame struct {
+	Time time.Time `json:"time"`
+	Data string    `json:"data"`
+}
+
+// Recorder writes every raw websocket frame to a JSONL file.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (r *Recorder) Write(frame []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(RecordedFrame{Time: time.Now(), Data: string(frame)})
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// ReadRecording calls fn for every frame of a recording in file order.
+func ReadRecording(path string, fn func(RecordedFrame) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordedFrameSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame RecordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return fmt.Errorf("recording line %d: %w", line, err)
+		}
+		if err := fn(frame); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Replay feeds a recording through the same dispatch path as StartListener.
+// speed scales the original pauses between frames (2 is twice as fast),
+// zero or less replays without pauses. Bets get the time of their frame.
+// Outgoing requests are skipped while replaying, except paper bets in dry
+// run; set UserId beforehand to receive our own notify channel.
+func (c *Client) Replay(path string, speed float64) error {
+	if c.channelNotify == "" && c.UserId != 0 {
+		c.channelNotify = fmt.Sprintf("notify#%d", c.UserId)
+		c.channelBalance = fmt.Sprintf("balance#%d", c.UserId)
+	}
+	c.replaying = true
+	defer func() { c.replaying = false }()
+
+	var last time.Time
+	return ReadRecording(path, func(frame RecordedFrame) error {
+		if speed > 0 && !last.IsZero() {
+			if pause := frame.Time.Sub(last); pause > 0 {
+				time.Sleep(time.Duration(float64(pause) / speed))
+			}
+		}
+		last = frame.Time
+		c.runDue(frame.Time)
+		c.replayTime = frame.Time
+		c.processFrame([]byte(frame.Data))
+		return nil
+	})
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RiskConfig holds the bankroll limits, a zero value disables the limit.
+type RiskConfig struct {
+	// StopLoss and TakeProfit stop betting once the session result reaches them
+	StopLoss   float32
+	TakeProfit float32
+	// MaxStakePerGame limits our total stake in a single game
+	MaxStakePerGame float32
+	// MaxRoomExposure limits our stake in unfinished games of one room
+	MaxRoomExposure float32
+	DailyLossLimit  float32
+	// CoolOff pauses betting after CoolOffLosses consecutive losses
+	CoolOffLosses int
+	CoolOff       time.Duration
+}
+
+// LoadRiskConfig reads the limits from a json file, cool_off is a duration
+// string such as "15m".
+func LoadRiskConfig(path string) (RiskConfig, error) {
+	var file struct {
+		StopLoss        float32 `json:"stop_loss"`
+		TakeProfit      float32 `json:"take_profit"`
+		MaxStakePerGame float32 `json:"max_stake_per_game"`
+		MaxRoomExposure float32 `json:"max_room_exposure"`
+		DailyLossLimit  float32 `json:"daily_loss_limit"`
+		CoolOffLosses   int     `json:"cool_off_losses"`
+		CoolOff         string  `json:"cool_off"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RiskConfig{}, err
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return RiskConfig{}, fmt.Errorf("cannot parse risk config: %w", err)
+	}
+	config := RiskConfig{
+		StopLoss:        file.StopLoss,
+		TakeProfit:      file.TakeProfit,
+		MaxStakePerGame: file.MaxStakePerGame,
+		MaxRoomExposure: file.MaxRoomExposure,
+		DailyLossLimit:  file.DailyLossLimit,
+		CoolOffLosses:   file.CoolOffLosses,
+	}
+	if file.CoolOff != "" {
+		config.CoolOff, err = time.ParseDuration(file.CoolOff)
+		if err != nil {
+			return RiskConfig{}, fmt.Errorf("cannot parse cool_off: %w", err)
+		}
+	}
+	return config, nil
+}
+
+type RiskRule string
+
+const (
+	RiskStopLoss     RiskRule = "stop_loss"
+	RiskTakeProfit   RiskRule = "take_profit"
+	RiskMaxStake     RiskRule = "max_stake_per_game"
+	RiskRoomExposure RiskRule = "max_room_exposure"
+	RiskDailyLoss    RiskRule = "daily_loss_limit"
+	RiskCoolOff      RiskRule = "cool_off"
+)
+
+// RiskError is returned by MakeBet when a bet breaks a risk rule.
+type RiskError struct {
+	Rule   RiskRule
+	GameId int
+	Amount float32
+	Limit  float32
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("bet %.2f in game %d rejected by %s (limit %.2f)", e.Amount, e.GameId, e.Rule, e.Limit)
+}
+
+// Settlement is our result in a finished game we had a stake in.
+type Settlement struct {
+	Game   *Game
+	Stake  float32
+	Payout float32
+	Profit float32
+	Won    bool
+	// Virtual is set for paper trading results
+	Virtual bool
+}
+
+type RiskManager struct {
+	config RiskConfig
+
+	mu           sync.Mutex
+	sessionPnL   float32
+	day          string
+	dailyPnL     float32
+	lossStreak   int
+	coolOffUntil time.Time
+	// open stakes by game id and the room they were placed in
+	openStakes map[int]float32
+	openRooms  map[int]int
+}
+
+func NewRiskManager(config RiskConfig) *RiskManager {
+	return &RiskManager{
+		config:     config,
+		openStakes: map[int]float32{},
+		openRooms:  map[int]int{},
+	}
+}
+
+func (r *RiskManager) Check(game *Game, amount float32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollDay()
+
+	reject := func(rule RiskRule, limit float32) error {
+		return &RiskError{Rule: rule, GameId: game.Id, Amount: amount, Limit: limit}
+	}
+	config := r.config
+	if config.StopLoss > 0 && r.sessionPnL <= -config.StopLoss {
+		return reject(RiskStopLoss, config.StopLoss)
+	}
+	if config.TakeProfit > 0 && r.sessionPnL >= config.TakeProfit {
+		return reject(RiskTakeProfit, config.TakeProfit)
+	}
+	if config.DailyLossLimit > 0 && r.dailyPnL <= -config.DailyLossLimit {
+		return reject(RiskDailyLoss, config.DailyLossLimit)
+	}
+	if time.Now().Before(r.coolOffUntil) {
+		return reject(RiskCoolOff, float32(config.CoolOffLosses))
+	}
+	if config.MaxStakePerGame > 0 && r.openStakes[game.Id]+amount > config.MaxStakePerGame {
+		return reject(RiskMaxStake, config.MaxStakePerGame)
+	}
+	if config.MaxRoomExposure > 0 {
+		exposure := amount
+		for gameId, stake := range r.openStakes {
+			if r.openRooms[gameId] == game.RoomId {
+				exposure += stake
+			}
+		}
+		if exposure > config.MaxRoomExposure {
+			return reject(RiskRoomExposure, config.MaxRoomExposure)
+		}
+	}
+	return nil
+}
+
+func (r *RiskManager) recordBet(game *Game, amount float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.openStakes[game.Id] += amount
+	r.openRooms[game.Id] = game.RoomId
+}
+
+func (r *RiskManager) recordSettlement(settlement *Settlement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollDay()
+	delete(r.openStakes, settlement.Game.Id)
+	delete(r.openRooms, settlement.Game.Id)
+
+	r.sessionPnL += settlement.Profit
+	r.dailyPnL += settlement.Profit
+	if settlement.Won {
+		r.lossStreak = 0
+		return
+	}
+	r.lossStreak++
+	if r.config.CoolOffLosses > 0 && r.lossStreak >= r.config.CoolOffLosses {
+		r.coolOffUntil = time.Now().Add(r.config.CoolOff)
+		r.lossStreak = 0
+	}
+}
+
+func (r *RiskManager) rollDay() {
+	day := time.Now().Format("2006-01-02")
+	if day != r.day {
+		r.day = day
+		r.dailyPnL = 0
+	}
+}
+
+// SessionPnL is the result of all settled games since the manager was created.
+func (r *RiskManager) SessionPnL() float32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessionPnL
+}
+
+func (c *Client) settle(game *Game) {
+	if game.BetNow <= 0 {
+		return
+	}
+	settlement := &Settlement{
+		Game:    game,
+		Stake:   game.BetNow,
+		Won:     game.Won,
+		Virtual: game.VirtualStake > 0,
+	}
+	if settlement.Won {
+		settlement.Payout = game.WinAmount()
+	}
+	settlement.Profit = settlement.Payout - settlement.Stake
+	if c.Risk != nil {
+		c.Risk.recordSettlement(settlement)
+	}
+	c.callSettlement(settlement)
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Room struct {
+	Id      int     `json:"id"`
+	Name    string  `json:"name"`
+	MaxBank float32 `json:"max_bank"`
+	MinBet  float32 `json:"min_bet"`
+	MaxBet  float32 `json:"max_bet"`
+	// Commission is the share of the bank kept by the site
+	Commission float32 `json:"commission"`
+	// Time is the countdown length in seconds
+	Time int `json:"time"`
+}
+
+// Rooms is the room catalogue by room id.
+type Rooms map[int]*Room
+
+const defaultCommission = 0.1
+
+// DefaultRooms returns the limits known at the time of writing, they are
+// used when no catalogue is configured.
+func DefaultRooms() Rooms {
+	return Rooms{
+		1: {Id: 1, Name: "classic", MaxBank: 500, MinBet: 1, MaxBet: 50, Commission: defaultCommission, Time: 15},
+		2: {Id: 2, Name: "bich", MaxBank: 50, MinBet: 0.1, MaxBet: 5, Commission: defaultCommission, Time: 10},
+		3: {Id: 3, Name: "dual", MaxBank: 100, MinBet: 10, MaxBet: 50, Commission: defaultCommission, Time: 1},
+		4: {Id: 4, Name: "rich", MaxBank: 2500, MinBet: 10, MaxBet: 250, Commission: defaultCommission, Time: 20},
+		5: {Id: 5, Name: "king", MaxBank: 10000, MinBet: 50, MaxBet: 1000, Commission: defaultCommission, Time: 25},
+		6: {Id: 6, Name: "epic", MaxBank: 50000, MinBet: 250, MaxBet: 5000, Commission: defaultCommission, Time: 30},
+	}
+}
+
+// LoadRooms reads a json list of rooms.
+func LoadRooms(path string) (Rooms, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Room
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse rooms file: %w", err)
+	}
+	rooms := Rooms{}
+	for _, room := range list {
+		if err := room.validate(); err != nil {
+			return nil, err
+		}
+		rooms[room.Id] = room
+	}
+	return rooms, nil
+}
+
+func (r Rooms) Get(id int) (*Room, error) {
+	room, ok := r[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown room %d", id)
+	}
+	return room, nil
+}
+
+func (r Rooms) ByName(name string) (*Room, error) {
+	for _, room := range r {
+		if room.Name == name {
+			return room, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown room %s", name)
+}
+
+func (r *Room) validate() error {
+	if r.Id == 0 || r.Name == "" {
+		return fmt.Errorf("room must have id and name")
+	}
+	if r.MinBet <= 0 || r.MaxBet < r.MinBet || r.MaxBank < r.MaxBet {
+		return fmt.Errorf("room %s: inconsistent limits", r.Name)
+	}
+	if r.Commission < 0 || r.Commission >= 1 {
+		return fmt.Errorf("room %s: commission must be in [0, 1)", r.Name)
+	}
+	return nil
+}
+
+var roomLimitRegexps = map[string]*regexp.Regexp{
+	"min_bet":    regexp.MustCompile(`(?i)минимальная ставка[^\d]*([\d.]+)`),
+	"max_bet":    regexp.MustCompile(`(?i)максимальная ставка[^\d]*([\d.]+)`),
+	"max_bank":   regexp.MustCompile(`(?i)максимальный банк[^\d]*([\d.]+)`),
+	"commission": regexp.MustCompile(`(?i)комиссия[^\d]*([\d.]+)\s*%`),
+	"time":       regexp.MustCompile(`(?i)таймер[^\d]*(\d+)`),
+}
+
+// LoadRoomsFromSite scrapes the limits from every room page, values missing
+// on a page are taken from the current catalogue.
+func (c *Client) LoadRoomsFromSite() (Rooms, error) {
+	rooms := Rooms{}
+	for id, known := range c.rooms {
+		html, err := c.getPage(roomUrl(known.Name))
+		if err != nil {
+			return nil, err
+		}
+		room := *known
+		for field, re := range roomLimitRegexps {
+			match := re.FindStringSubmatch(html)
+			if match == nil {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSuffix(match[1], "."), 32)
+			if err != nil {
+				continue
+			}
+			switch field {
+			case "min_bet":
+				room.MinBet = float32(value)
+			case "max_bet":
+				room.MaxBet = float32(value)
+			case "max_bank":
+				room.MaxBank = float32(value)
+			case "commission":
+				room.Commission = float32(value / 100)
+			case "time":
+				room.Time = int(value)
+			}
+		}
+		if err := room.validate(); err != nil {
+			return nil, fmt.Errorf("scraped %w", err)
+		}
+		rooms[id] = &room
+	}
+	return rooms, nil
+}
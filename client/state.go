@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+type GameState int
+
+const (
+	GameStateOpen GameState = iota
+	GameStateBetting
+	GameStateCountdown
+	GameStateDrawing
+	GameStateFinished
+)
+
+var gameStateNames = map[GameState]string{
+	GameStateOpen:      "open",
+	GameStateBetting:   "betting",
+	GameStateCountdown: "countdown",
+	GameStateDrawing:   "drawing",
+	GameStateFinished:  "finished",
+}
+
+func (s GameState) String() string {
+	if name, ok := gameStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+// gameTransitions lists the states reachable from each state, staying in
+// the same state is listed explicitly where repeated events are normal.
+var gameTransitions = map[GameState][]GameState{
+	GameStateOpen:      {GameStateBetting, GameStateCountdown},
+	GameStateBetting:   {GameStateBetting, GameStateCountdown, GameStateDrawing, GameStateFinished},
+	GameStateCountdown: {GameStateCountdown, GameStateDrawing, GameStateFinished},
+	GameStateDrawing:   {GameStateDrawing, GameStateFinished},
+	GameStateFinished:  {},
+}
+
+func (g *Game) setState(state GameState) error {
+	for _, allowed := range gameTransitions[g.State] {
+		if allowed == state {
+			g.State = state
+			return nil
+		}
+	}
+	return fmt.Errorf("game %d: impossible transition %s -> %s", g.Id, g.State, state)
+}
+
+// AcceptsBets reports whether the game is not closing yet.
+func (g *Game) AcceptsBets() bool {
+	return g.State < GameStateDrawing
+}
+
+const (
+	eventBufferTTL  = time.Minute
+	eventBufferSize = 200
+)
+
+type bufferedEvent struct {
+	received time.Time
+	event    interface{}
+}
+
+// bufferEvent keeps an event for a game we have not seen new_game for yet,
+// it is replayed once the game appears.
+func (c *Client) bufferEvent(gameId int, event interface{}) {
+	now := time.Now()
+	for id, events := range c.pendingEvents {
+		if len(events) > 0 && now.Sub(events[len(events)-1].received) > eventBufferTTL {
+			fmt.Println("dropping", len(events), "buffered events for game", id)
+			delete(c.pendingEvents, id)
+		}
+	}
+	if len(c.pendingEvents[gameId]) >= eventBufferSize {
+		fmt.Println("event buffer overflow for game", gameId)
+		return
+	}
+	c.pendingEvents[gameId] = append(c.pendingEvents[gameId], bufferedEvent{now, event})
+}
+
+func (c *Client) flushEvents(gameId int) {
+	events := c.pendingEvents[gameId]
+	delete(c.pendingEvents, gameId)
+	for _, buffered := range events {
+		switch event := buffered.event.(type) {
+		case *NewBetEvent:
+			c.processNewBetEvent(event)
+		case *TimeEvent:
+			c.processTimeEvent(event)
+		case *EndGameEvent:
+			c.processEndGameEvent(event)
+		}
+	}
+}
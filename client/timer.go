@@ -0,0 +1,161 @@
+package client
+
+import (
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/metrics"
+)
+
+const (
+	// defaultBetLatency is assumed until the first bet is measured
+	defaultBetLatency = 300 * time.Millisecond
+	// betLatencyWeight is the weight of the newest sample in the average
+	betLatencyWeight = 0.3
+)
+
+// TimerRunning reports whether the countdown has started, the site only
+// starts it once enough players joined.
+func (g *Game) TimerRunning() bool {
+	return !g.deadline.IsZero()
+}
+
+// TimeLeft is the local estimate of the countdown, it is the full room
+// time while the countdown has not started.
+func (g *Game) TimeLeft() time.Duration {
+	if !g.TimerRunning() {
+		return time.Duration(g.TimeNow) * time.Second
+	}
+	left := g.deadline.Sub(g.now())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// syncTimer moves the local deadline to the value pushed by time_game.
+func (g *Game) syncTimer(seconds int) {
+	g.TimeNow = seconds
+	g.deadline = g.now().Add(time.Duration(seconds) * time.Second)
+}
+
+// now reads the game clock, games built without NewGame use the wall clock.
+func (g *Game) now() time.Time {
+	if g.clock == nil {
+		return time.Now()
+	}
+	return g.clock()
+}
+
+type scheduledAction struct {
+	game   *Game
+	before time.Duration
+	action func(*Game)
+	timer  *time.Timer
+	// at is the due time while replaying, replay has no wall clock timers
+	at   time.Time
+	done bool
+}
+
+// ScheduleBefore runs action when the game countdown reaches before,
+// moved earlier by the measured bet latency. Until the countdown starts the
+// action waits, and it is dropped when the game closes first. The action is
+// called under the client lock, so it can use the game and MakeBet directly.
+// Callers must hold the client lock too, as game update handlers do.
+func (c *Client) ScheduleBefore(game *Game, before time.Duration, action func(*Game)) {
+	scheduled := &scheduledAction{game: game, before: before, action: action}
+	c.scheduled[game.Id] = append(c.scheduled[game.Id], scheduled)
+	if game.TimerRunning() {
+		c.arm(scheduled)
+	}
+}
+
+// BetLatency is the moving average of bet request durations.
+func (c *Client) BetLatency() time.Duration {
+	c.latencyMu.Lock()
+	defer c.latencyMu.Unlock()
+	if c.betLatency == 0 {
+		return defaultBetLatency
+	}
+	return c.betLatency
+}
+
+func (c *Client) measureBetLatency(latency time.Duration) {
+	metrics.BetLatency(latency)
+	c.latencyMu.Lock()
+	defer c.latencyMu.Unlock()
+	if c.betLatency == 0 {
+		c.betLatency = latency
+		return
+	}
+	c.betLatency = time.Duration(betLatencyWeight*float64(latency) + (1-betLatencyWeight)*float64(c.betLatency))
+}
+
+func (c *Client) arm(scheduled *scheduledAction) {
+	if scheduled.timer != nil {
+		scheduled.timer.Stop()
+	}
+	wait := scheduled.game.TimeLeft() - scheduled.before - c.BetLatency()
+	if wait < 0 {
+		wait = 0
+	}
+	if c.replaying {
+		scheduled.at = c.now().Add(wait)
+		return
+	}
+	scheduled.timer = time.AfterFunc(wait, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if scheduled.done || !scheduled.game.AcceptsBets() {
+			return
+		}
+		scheduled.done = true
+		scheduled.action(scheduled.game)
+	})
+}
+
+// runDue fires the replayed actions due until the given frame time, in
+// order and with the replay clock set to their due time.
+func (c *Client) runDue(until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		var next *scheduledAction
+		for _, actions := range c.scheduled {
+			for _, scheduled := range actions {
+				if scheduled.done || scheduled.at.IsZero() || scheduled.at.After(until) {
+					continue
+				}
+				if next == nil || scheduled.at.Before(next.at) {
+					next = scheduled
+				}
+			}
+		}
+		if next == nil {
+			return
+		}
+		next.done = true
+		c.replayTime = next.at
+		if next.game.AcceptsBets() {
+			next.action(next.game)
+		}
+	}
+}
+
+// reschedule rearms the pending actions of a game after a timer sync.
+func (c *Client) reschedule(game *Game) {
+	for _, scheduled := range c.scheduled[game.Id] {
+		if !scheduled.done {
+			c.arm(scheduled)
+		}
+	}
+}
+
+func (c *Client) cancelScheduled(gameId int) {
+	for _, scheduled := range c.scheduled[gameId] {
+		scheduled.done = true
+		if scheduled.timer != nil {
+			scheduled.timer.Stop()
+		}
+	}
+	delete(c.scheduled, gameId)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/backtest"
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/storage"
+	"github.com/Qwerty10291/csgf_bot/strategy"
+)
+
+func main() {
+	recordingPath := flag.String("recording", "", "websocket recording to replay")
+	dbPath := flag.String("db", "", "history database to replay instead of a recording")
+	from := flag.String("from", "", "first day of stored games, 2006-01-02")
+	to := flag.String("to", "", "day after the last day of stored games, 2006-01-02")
+	strategies := flag.String("strategies", "", "comma separated strategy config files")
+	roomsPath := flag.String("rooms", "", "json file with the room catalogue")
+	balance := flag.Float64("balance", 1000, "starting balance")
+	recorded := flag.Bool("recorded-result", false, "settle with the recorded winning ticket instead of drawing")
+	seed := flag.Int64("seed", 1, "random seed for drawn results")
+	flag.Parse()
+
+	if (*recordingPath == "") == (*dbPath == "") || *strategies == "" {
+		flag.Usage()
+		return
+	}
+
+	config := backtest.Config{
+		Balance:        float32(*balance),
+		RecordedResult: *recorded,
+		Seed:           *seed,
+	}
+	if *roomsPath != "" {
+		rooms, err := client.LoadRooms(*roomsPath)
+		if err != nil {
+			panic(err)
+		}
+		config.Rooms = rooms
+	}
+
+	var timelines []*backtest.Timeline
+	if *dbPath != "" {
+		db, err := storage.Open(*dbPath)
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		filter := storage.Filter{}
+		if filter.From, err = parseDay(*from); err != nil {
+			panic(err)
+		}
+		if filter.To, err = parseDay(*to); err != nil {
+			panic(err)
+		}
+		timelines, err = backtest.LoadStorage(db, filter)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		var err error
+		timelines, err = backtest.LoadRecording(*recordingPath, config.Rooms)
+		if err != nil {
+			panic(err)
+		}
+	}
+	fmt.Println("loaded", len(timelines), "games")
+
+	for _, path := range strings.Split(*strategies, ",") {
+		strategyConfig, err := strategy.LoadConfig(path)
+		if err != nil {
+			panic(err)
+		}
+		s, err := strategy.FromConfig(strategyConfig)
+		if err != nil {
+			panic(err)
+		}
+		for _, result := range backtest.Run(s, timelines, config) {
+			fmt.Println(result)
+		}
+	}
+}
+
+func parseDay(day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", day, time.Local)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/chat"
+	"github.com/Qwerty10291/csgf_bot/export"
+	"github.com/Qwerty10291/csgf_bot/storage"
+	"github.com/Qwerty10291/csgf_bot/store"
+)
+
+func main() {
+	dbPath := flag.String("db", "", "history database")
+	statePath := flag.String("state", "", "plugin state with the math game rounds")
+	from := flag.String("from", "", "first day, 2006-01-02")
+	to := flag.String("to", "", "day after the last day, 2006-01-02")
+	format := flag.String("format", export.FormatCSV, "csv or jsonl")
+	out := flag.String("out", ".", "directory for the exported files")
+	tables := flag.String("tables", "games,bets,chat,transfers,math_rounds", "comma separated tables to export")
+	flag.Parse()
+
+	filter := storage.Filter{}
+	var err error
+	if filter.From, err = parseDay(*from); err != nil {
+		panic(err)
+	}
+	if filter.To, err = parseDay(*to); err != nil {
+		panic(err)
+	}
+
+	var db *storage.DB
+	if *dbPath != "" {
+		db, err = storage.Open(*dbPath)
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		panic(err)
+	}
+
+	for _, name := range strings.Split(*tables, ",") {
+		table, err := loadTable(strings.TrimSpace(name), db, *statePath, filter)
+		if err != nil {
+			panic(err)
+		}
+		path := filepath.Join(*out, table.Name+"."+*format)
+		file, err := os.Create(path)
+		if err != nil {
+			panic(err)
+		}
+		if err := export.Write(file, *format, table); err != nil {
+			panic(err)
+		}
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+		fmt.Println("exported", len(table.Rows), "rows to", path)
+	}
+}
+
+func loadTable(name string, db *storage.DB, statePath string, filter storage.Filter) (*export.Table, error) {
+	if name == "math_rounds" {
+		if statePath == "" {
+			return nil, fmt.Errorf("math_rounds requires -state")
+		}
+		state, err := store.Open(statePath)
+		if err != nil {
+			return nil, err
+		}
+		defer state.Close()
+		rounds, err := chat.ReadMathRounds(store.Namespace(state, "math_game"), filter.From, filter.To)
+		if err != nil {
+			return nil, err
+		}
+		return export.MathRounds(rounds), nil
+	}
+
+	if db == nil {
+		return nil, fmt.Errorf("%s requires -db", name)
+	}
+	switch name {
+	case "games":
+		games, err := db.Games(filter)
+		if err != nil {
+			return nil, err
+		}
+		return export.Games(games), nil
+	case "bets":
+		bets, err := db.Bets(filter)
+		if err != nil {
+			return nil, err
+		}
+		return export.Bets(bets), nil
+	case "chat":
+		messages, err := db.ChatMessages(filter)
+		if err != nil {
+			return nil, err
+		}
+		return export.ChatMessages(messages), nil
+	case "transfers":
+		transfers, err := db.Transfers(filter)
+		if err != nil {
+			return nil, err
+		}
+		return export.Transfers(transfers), nil
+	}
+	return nil, fmt.Errorf("unknown table %q", name)
+}
+
+func parseDay(day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", day, time.Local)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/importer"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+func main() {
+	dbPath := flag.String("db", "", "history database")
+	roomsPath := flag.String("rooms", "", "json file with the room catalogue")
+	login := flag.String("login", "", "vk login")
+	password := flag.String("password", "", "vk password")
+	pages := flag.Int("pages", 0, "number of history pages to read, 0 reads all")
+	interval := flag.Duration("interval", 2*time.Second, "pause between page loads, 0 does not pause")
+	refresh := flag.Bool("refresh", false, "load games that are already stored again")
+	restart := flag.Bool("restart", false, "forget the saved progress and start from the first page")
+	flag.Parse()
+
+	if *dbPath == "" {
+		flag.Usage()
+		return
+	}
+	var rooms client.Rooms
+	if *roomsPath != "" {
+		var err error
+		rooms, err = client.LoadRooms(*roomsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+	db, err := storage.Open(*dbPath)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	csgfClient := client.NewClient(client.ClientConfig{
+		VkLogin:    *login,
+		VkPassword: *password,
+		Rooms:      rooms,
+	})
+	if err := csgfClient.Login(); err != nil {
+		panic(err)
+	}
+
+	imp := importer.NewImporter(csgfClient, db)
+	imp.Interval = *interval
+	imp.Pages = *pages
+	imp.Refresh = *refresh
+	if *restart {
+		if err := imp.Reset(); err != nil {
+			panic(err)
+		}
+	}
+	imported, err := imp.Run()
+	fmt.Println("imported", imported, "games")
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/analytics"
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+func main() {
+	dbPath := flag.String("db", "", "history database")
+	roomsPath := flag.String("rooms", "", "json file with the room catalogue")
+	from := flag.String("from", "", "first day, 2006-01-02")
+	to := flag.String("to", "", "day after the last day, 2006-01-02")
+	players := flag.Int("players", 20, "number of players to report, by amount wagered")
+	flag.Parse()
+
+	if *dbPath == "" {
+		flag.Usage()
+		return
+	}
+	rooms := client.DefaultRooms()
+	if *roomsPath != "" {
+		var err error
+		rooms, err = client.LoadRooms(*roomsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+	db, err := storage.Open(*dbPath)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	filter := storage.Filter{}
+	if filter.From, err = parseDay(*from); err != nil {
+		panic(err)
+	}
+	if filter.To, err = parseDay(*to); err != nil {
+		panic(err)
+	}
+	games, err := db.Games(filter)
+	if err != nil {
+		panic(err)
+	}
+	bets, err := db.Bets(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("ROOMS")
+	fmt.Printf("%-8s %6s %10s %8s %6s %8s %8s  %s\n", "room", "games", "avg bank", "players", "bets", "median", "p90", strings.Join(analytics.BetSizeLabels(), " "))
+	for _, room := range analytics.Rooms(games, bets, rooms) {
+		sizes := []string{}
+		for _, count := range room.BetSizes {
+			sizes = append(sizes, fmt.Sprint(count))
+		}
+		fmt.Printf("%-8s %6d %10.2f %8.2f %6d %8.2f %8.2f  %s\n", room.Room, room.Games, room.AvgBank, room.AvgPlayers,
+			room.Bets, room.MedianBet, room.P90Bet, strings.Join(sizes, " "))
+	}
+
+	fmt.Println("\nGAMES BY HOUR")
+	for _, room := range analytics.Rooms(games, bets, rooms) {
+		hours := []string{}
+		for _, count := range room.GamesByHour {
+			hours = append(hours, fmt.Sprintf("%3d", count))
+		}
+		fmt.Printf("%-8s %s\n", room.Room, strings.Join(hours, ""))
+	}
+
+	fmt.Println("\nPLAYERS")
+	fmt.Printf("%-10s %-20s %6s %12s %6s %9s %9s\n", "user", "name", "games", "wagered", "wins", "win rate", "expected")
+	for i, player := range analytics.Players(games, bets) {
+		if i >= *players {
+			break
+		}
+		fmt.Printf("%-10d %-20s %6d %12s %6d %8.1f%% %8.1f%%\n", player.UserId, player.Username, player.Games,
+			player.Wagered, player.Wins, player.WinRate()*100, player.ExpectedWinRate()*100)
+	}
+}
+
+func parseDay(day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", day, time.Local)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Qwerty10291/csgf_bot/backtest"
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/strategy"
+)
+
+func main() {
+	strategyPath := flag.String("strategy", "", "strategy config file")
+	roomsPath := flag.String("rooms", "", "json file with the room catalogue")
+	roomName := flag.String("room", "classic", "room to simulate")
+	balance := flag.Float64("balance", 1000, "starting balance")
+	runs := flag.Int("runs", 10000, "number of simulated sequences")
+	games := flag.Int("games", 500, "games in every sequence")
+	othersMin := flag.Float64("others-min", 10, "minimal stake of the other players")
+	othersMax := flag.Float64("others-max", 200, "maximal stake of the other players")
+	stopLoss := flag.Float64("stop-loss", 0, "stop a sequence after losing this much, 0 disables it")
+	seed := flag.Int64("seed", 1, "random seed")
+	flag.Parse()
+
+	if *strategyPath == "" {
+		flag.Usage()
+		return
+	}
+
+	rooms := client.DefaultRooms()
+	if *roomsPath != "" {
+		var err error
+		rooms, err = client.LoadRooms(*roomsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+	room, err := rooms.ByName(*roomName)
+	if err != nil {
+		panic(err)
+	}
+
+	strategyConfig, err := strategy.LoadConfig(*strategyPath)
+	if err != nil {
+		panic(err)
+	}
+	s, err := strategy.FromConfig(strategyConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	report := backtest.MonteCarlo(s, backtest.MonteCarloConfig{
+		Room:      room,
+		Balance:   float32(*balance),
+		Runs:      *runs,
+		Games:     *games,
+		OthersMin: float32(*othersMin),
+		OthersMax: float32(*othersMax),
+		StopLoss:  float32(*stopLoss),
+		Seed:      *seed,
+	})
+	fmt.Println(s.Name(), "in", room.Name)
+	fmt.Println(report)
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+// Table is a set of rows with stable column names. Cells are strings,
+// ints, bools, storage.Money or time.Time.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Write writes the table as csv with a header row or as json lines with an
+// object per row. Money is written as an exact decimal and times in RFC3339.
+func Write(w io.Writer, format string, table *Table) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, table)
+	case FormatJSONL:
+		return writeJSONL(w, table)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func writeCSV(w io.Writer, table *Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, cell := range row {
+			record[i] = formatCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSONL(w io.Writer, table *Table) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range table.Rows {
+		object := make(map[string]interface{}, len(row))
+		for i, cell := range row {
+			switch value := cell.(type) {
+			case storage.Money:
+				object[table.Columns[i]] = json.Number(value.String())
+			case time.Time:
+				object[table.Columns[i]] = formatCell(value)
+			default:
+				object[table.Columns[i]] = value
+			}
+		}
+		if err := encoder.Encode(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatCell(cell interface{}) string {
+	switch value := cell.(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case bool:
+		return strconv.FormatBool(value)
+	case storage.Money:
+		return value.String()
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"github.com/Qwerty10291/csgf_bot/chat"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+func Games(games []storage.GameRecord) *Table {
+	table := &Table{
+		Name: "games",
+		Columns: []string{"game_id", "room_id", "room", "created_at", "finished_at", "bank", "players",
+			"winner_id", "winner_name", "ticket", "commission", "hash", "seed", "won"},
+	}
+	for _, game := range games {
+		table.Rows = append(table.Rows, []interface{}{game.Id, game.RoomId, game.Room, game.Created, game.Finished,
+			game.Bank, game.Players, game.WinnerId, game.WinnerName, game.Ticket, game.Commission, game.Hash, game.Seed, game.Won})
+	}
+	return table
+}
+
+func Bets(bets []storage.BetRecord) *Table {
+	table := &Table{
+		Name:    "bets",
+		Columns: []string{"game_id", "user_id", "username", "amount", "placed_at", "ticket_from", "ticket_to", "virtual"},
+	}
+	for _, bet := range bets {
+		table.Rows = append(table.Rows, []interface{}{bet.GameId, bet.UserId, bet.Username, bet.Amount, bet.Placed,
+			bet.TicketFrom, bet.TicketTo, bet.Virtual})
+	}
+	return table
+}
+
+func ChatMessages(messages []storage.ChatRecord) *Table {
+	table := &Table{
+		Name:    "chat",
+		Columns: []string{"user_id", "username", "message", "received_at"},
+	}
+	for _, message := range messages {
+		table.Rows = append(table.Rows, []interface{}{message.UserId, message.Username, message.Message, message.Received})
+	}
+	return table
+}
+
+func Transfers(transfers []storage.TransferRecord) *Table {
+	table := &Table{
+		Name:    "transfers",
+		Columns: []string{"from_user", "amount", "received_at"},
+	}
+	for _, transfer := range transfers {
+		table.Rows = append(table.Rows, []interface{}{transfer.FromUser, transfer.Amount, transfer.Received})
+	}
+	return table
+}
+
+func MathRounds(rounds []chat.MathRound) *Table {
+	table := &Table{
+		Name:    "math_rounds",
+		Columns: []string{"creator", "expression", "answer", "bank", "winner_id", "winner", "solved_at"},
+	}
+	for _, round := range rounds {
+		table.Rows = append(table.Rows, []interface{}{round.Creator, round.Expression, round.Answer,
+			storage.MoneyFromFloat(round.Bank), round.WinnerId, round.Winner, round.Solved})
+	}
+	return table
+}
@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/storage"
+)
+
+// progress keys in storage: the newest game id of the finished catch up,
+// and the backfill cursor with the last page read and the oldest game id
+// seen on it.
+const (
+	progressNewest = "history_newest"
+	progressPage   = "history_page"
+	progressOldest = "history_oldest"
+)
+
+var (
+	historyGameRegexp = regexp.MustCompile(`href="\/game\/(\d+)"`)
+	gameRoomRegexp    = regexp.MustCompile(`data-room="(\w+)"`)
+	gameDateRegexp    = regexp.MustCompile(`data-date="(\d+)"`)
+)
+
+func historyUrl(page int) string {
+	return fmt.Sprintf("https://csgf.live/history?page=%d", page)
+}
+
+// Importer pages through the site history and stores the games the bot did
+// not see live. The client must be logged in, the websocket is not needed.
+type Importer struct {
+	client *client.Client
+	db     *storage.DB
+	rooms  client.Rooms
+	// Interval is the pause between two page loads, zero does not pause
+	Interval time.Duration
+	// Pages limits the number of history pages of a run, zero reads until an empty page
+	Pages int
+	// Refresh loads games that are already stored again
+	Refresh bool
+
+	ticker *time.Ticker
+	// pages is the number of history pages read in this run
+	pages int
+}
+
+func NewImporter(c *client.Client, db *storage.DB) *Importer {
+	return &Importer{
+		client:   c,
+		db:       db,
+		rooms:    c.Catalogue(),
+		Interval: 2 * time.Second,
+	}
+}
+
+// Reset forgets the saved progress, the next run starts from the first page.
+func (imp *Importer) Reset() error {
+	for _, name := range []string{progressNewest, progressPage, progressOldest} {
+		if err := imp.db.SetProgress(name, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run returns the number of imported games. The history lists newest games
+// first, so the pages shift as games are played and progress is kept by
+// game id: a run first reads from page one down to the newest game of the
+// last run, then continues the backfill of older pages.
+func (imp *Importer) Run() (int, error) {
+	if imp.Interval > 0 {
+		imp.ticker = time.NewTicker(imp.Interval)
+		defer imp.ticker.Stop()
+	}
+	imp.pages = 0
+	imported, done, err := imp.catchUp()
+	if err != nil || done {
+		return imported, err
+	}
+	backfilled, err := imp.backfill()
+	return imported + backfilled, err
+}
+
+// catchUp imports the games newer than the newest one of the last run. It
+// reports done when the page limit is reached.
+func (imp *Importer) catchUp() (int, bool, error) {
+	last, err := imp.db.Progress(progressNewest)
+	if err != nil || last == 0 {
+		// the first run reads everything in the backfill
+		return 0, false, err
+	}
+	imported, newest := 0, last
+	for page := 1; ; page++ {
+		if imp.limitReached() {
+			return imported, true, nil
+		}
+		ids, err := imp.historyPage(page)
+		if err != nil || len(ids) == 0 {
+			return imported, false, err
+		}
+		reached := false
+		newer := []int{}
+		for _, id := range ids {
+			if id <= last {
+				reached = true
+				continue
+			}
+			newer = append(newer, id)
+			if id > newest {
+				newest = id
+			}
+		}
+		count, err := imp.importGames(newer)
+		imported += count
+		if err != nil {
+			return imported, false, err
+		}
+		if reached {
+			break
+		}
+	}
+	// saved only once complete, an interrupted catch up starts over
+	return imported, false, imp.db.SetProgress(progressNewest, newest)
+}
+
+// backfill continues from the page after the last backfilled one. Pages
+// that moved down since then only hold games newer than the oldest seen
+// and are passed over.
+func (imp *Importer) backfill() (int, error) {
+	page, err := imp.db.Progress(progressPage)
+	if err != nil {
+		return 0, err
+	}
+	oldest, err := imp.db.Progress(progressOldest)
+	if err != nil {
+		return 0, err
+	}
+	newest, err := imp.db.Progress(progressNewest)
+	if err != nil {
+		return 0, err
+	}
+	// only the first run takes the newest game from the backfill, later
+	// runs leave it to the catch up
+	first := newest == 0
+	imported := 0
+	for page++; !imp.limitReached(); page++ {
+		ids, err := imp.historyPage(page)
+		if err != nil {
+			return imported, err
+		}
+		if len(ids) == 0 {
+			fmt.Println("history page", page, "is empty, backfill is done")
+			break
+		}
+		older := []int{}
+		for _, id := range ids {
+			if first && id > newest {
+				newest = id
+			}
+			if oldest != 0 && id >= oldest {
+				continue
+			}
+			older = append(older, id)
+		}
+		count, err := imp.importGames(older)
+		imported += count
+		if err != nil {
+			return imported, err
+		}
+		for _, id := range older {
+			if oldest == 0 || id < oldest {
+				oldest = id
+			}
+		}
+		if err := imp.saveBackfill(page, oldest, newest); err != nil {
+			return imported, err
+		}
+		fmt.Println("history page", page, "done,", imported, "games imported")
+	}
+	return imported, nil
+}
+
+func (imp *Importer) saveBackfill(page int, oldest int, newest int) error {
+	if err := imp.db.SetProgress(progressNewest, newest); err != nil {
+		return err
+	}
+	if err := imp.db.SetProgress(progressOldest, oldest); err != nil {
+		return err
+	}
+	return imp.db.SetProgress(progressPage, page)
+}
+
+func (imp *Importer) limitReached() bool {
+	return imp.Pages > 0 && imp.pages >= imp.Pages
+}
+
+// wait keeps the pause between two page loads.
+func (imp *Importer) wait() {
+	if imp.ticker != nil {
+		<-imp.ticker.C
+	}
+}
+
+func (imp *Importer) historyPage(page int) ([]int, error) {
+	imp.pages++
+	imp.wait()
+	html, err := imp.client.FetchPage(historyUrl(page))
+	if err != nil {
+		return nil, err
+	}
+	return parseHistoryPage(html), nil
+}
+
+func (imp *Importer) importGames(ids []int) (int, error) {
+	imported := 0
+	for _, id := range ids {
+		if !imp.Refresh {
+			known, err := imp.db.HasGame(id)
+			if err != nil {
+				return imported, err
+			}
+			if known {
+				continue
+			}
+		}
+		imp.wait()
+		if err := imp.importGame(id); err != nil {
+			// one broken page should not stop the whole import
+			fmt.Println("cannot import game", id, err)
+			continue
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+func (imp *Importer) importGame(id int) error {
+	html, err := imp.client.FetchPage(client.GameUrl(id))
+	if err != nil {
+		return err
+	}
+	game, bets, err := parseGamePage(id, html, imp.rooms)
+	if err != nil {
+		return err
+	}
+	return imp.db.UpsertGame(game, bets)
+}
+
+// parseHistoryPage returns the game ids of a history page in page order.
+func parseHistoryPage(html string) []int {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, match := range historyGameRegexp.FindAllStringSubmatch(html, -1) {
+		id, err := strconv.Atoi(match[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parseGamePage reads a finished game page. The page has no bet times, all
+// bets get the finish time of the game.
+func parseGamePage(id int, html string, rooms client.Rooms) (storage.GameRecord, []storage.BetRecord, error) {
+	game := storage.GameRecord{Id: id}
+	roomData := gameRoomRegexp.FindStringSubmatch(html)
+	if roomData == nil {
+		return game, nil, fmt.Errorf("cannot find room")
+	}
+	room, err := rooms.ByName(roomData[1])
+	if err != nil {
+		return game, nil, err
+	}
+	game.RoomId = room.Id
+	game.Room = room.Name
+
+	result, err := client.ParseGameResult(id, html)
+	if err != nil {
+		return game, nil, err
+	}
+	game.WinnerId = result.WinnerId
+	game.WinnerName = result.WinnerName
+	game.Ticket = result.Ticket
+	game.Hash = result.Hash
+	game.Seed = result.Seed
+	if dateData := gameDateRegexp.FindStringSubmatch(html); dateData != nil {
+		seconds, _ := strconv.ParseInt(dateData[1], 10, 64)
+		game.Finished = time.Unix(seconds, 0)
+	}
+	game.Created = game.Finished
+
+	var total storage.Money
+	bets := []storage.BetRecord{}
+	players := map[int]bool{}
+	for _, bet := range client.ParsePageBets(html) {
+		players[bet.UserId] = true
+		amount := storage.MoneyFromFloat(bet.Amount)
+		total += amount
+		bets = append(bets, storage.BetRecord{
+			GameId:     id,
+			UserId:     bet.UserId,
+			Username:   bet.Username,
+			Amount:     amount,
+			Placed:     game.Finished,
+			TicketFrom: bet.TicketFrom,
+			TicketTo:   bet.TicketTo,
+		})
+	}
+	if len(bets) == 0 {
+		return game, nil, fmt.Errorf("cannot find bets")
+	}
+	game.Players = len(players)
+	game.Bank = storage.MoneyFromFloat(client.ParsePageBank(html))
+	if game.Bank == 0 {
+		game.Bank = total
+	}
+	return game, bets, nil
+}
@@ -1,21 +1,150 @@
 package main
 
 import (
-	"github.com/Qwerty10291/csgf_bot/client"
+	"flag"
+	"fmt"
+
+	"github.com/Qwerty10291/csgf_bot/alerts"
 	"github.com/Qwerty10291/csgf_bot/chat"
+	"github.com/Qwerty10291/csgf_bot/client"
+	"github.com/Qwerty10291/csgf_bot/metrics"
+	"github.com/Qwerty10291/csgf_bot/notify"
+	"github.com/Qwerty10291/csgf_bot/storage"
+	"github.com/Qwerty10291/csgf_bot/store"
+	"github.com/Qwerty10291/csgf_bot/strategy"
 )
 
 func main() {
+	recordPath := flag.String("record", "", "write raw websocket frames to this file")
+	replayPath := flag.String("replay", "", "replay a recorded file instead of connecting")
+	replaySpeed := flag.Float64("speed", 1, "replay speed multiplier, 0 replays without pauses")
+	replayUser := flag.Int("user", 0, "our user id while replaying")
+	roomsPath := flag.String("rooms", "", "json file with the room catalogue")
+	scrapeRooms := flag.Bool("scrape-rooms", false, "load room limits from the site on connect")
+	strategyPath := flag.String("strategy", "", "json file with the betting strategy config")
+	riskPath := flag.String("risk", "", "json file with the bankroll limits")
+	dryRun := flag.Bool("dry-run", false, "record virtual bets instead of placing them")
+	dbPath := flag.String("db", "", "sqlite file to store the game history in")
+	statePath := flag.String("state", "", "plugin state: a directory for json files or a .db file, memory when empty")
+	alertsPath := flag.String("alerts", "", "json file with the watchlist alert rules")
+	notifyPath := flag.String("notify", "", "json file with the outbound webhooks")
+	metricsAddress := flag.String("metrics", "", "address to serve prometheus /metrics on, such as :9100")
+	paperBalance := flag.Float64("paper-balance", 0, "starting virtual balance in dry run, the real one when zero")
+	flag.Parse()
+
+	var rooms client.Rooms
+	if *roomsPath != "" {
+		var err error
+		rooms, err = client.LoadRooms(*roomsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var risk *client.RiskManager
+	if *riskPath != "" {
+		riskConfig, err := client.LoadRiskConfig(*riskPath)
+		if err != nil {
+			panic(err)
+		}
+		risk = client.NewRiskManager(riskConfig)
+	}
+
 	csgfClient := client.NewClient(client.ClientConfig{
-		VkLogin:    "login",
-		VkPassword: "password",
+		VkLogin:      "login",
+		VkPassword:   "password",
+		Rooms:        rooms,
+		ScrapeRooms:  *scrapeRooms,
+		Risk:         risk,
+		DryRun:       *dryRun,
+		PaperBalance: float32(*paperBalance),
+		BetRejectedHandler: func(game *client.Game, err *client.RiskError) {
+			fmt.Println("risk manager:", err)
+		},
+		SettlementHandler: func(settlement *client.Settlement) {
+			fmt.Printf("game %d settled: stake %.2f, profit %.2f\n", settlement.Game.Id, settlement.Stake, settlement.Profit)
+		},
 	})
 
-	chat.NewMathChatGame(csgfClient, 0.05)
-	err := csgfClient.Connect()
-	if err != nil{
+	if *metricsAddress != "" {
+		go func() {
+			if err := metrics.Serve(*metricsAddress); err != nil {
+				fmt.Println("metrics server error", err)
+			}
+		}()
+	}
+
+	state, err := store.Open(*statePath)
+	if err != nil {
+		panic(err)
+	}
+	defer state.Close()
+	mathGame := chat.NewMathChatGame(csgfClient, 0.05, store.Namespace(state, "math_game"))
+
+	if *notifyPath != "" {
+		config, err := notify.LoadConfig(*notifyPath)
+		if err != nil {
+			panic(err)
+		}
+		notifier := notify.NewNotifier(config)
+		defer notifier.Close()
+		notifier.Attach(csgfClient)
+		notifier.AttachMathGame(mathGame)
+	}
+
+	if *dbPath != "" {
+		db, err := storage.Open(*dbPath)
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		db.Attach(csgfClient)
+	}
+
+	if *strategyPath != "" {
+		config, err := strategy.LoadConfig(*strategyPath)
+		if err != nil {
+			panic(err)
+		}
+		s, err := strategy.FromConfig(config)
+		if err != nil {
+			panic(err)
+		}
+		strategy.NewEngine(csgfClient, s)
+	}
+
+	if *alertsPath != "" {
+		config, err := alerts.LoadConfig(*alertsPath)
+		if err != nil {
+			panic(err)
+		}
+		watcher := alerts.NewWatcher(csgfClient, config)
+		defer watcher.Close()
+	}
+
+	if *replayPath != "" {
+		csgfClient.UserId = *replayUser
+		csgfClient.Balance = float32(*paperBalance)
+		err := csgfClient.Replay(*replayPath, *replaySpeed)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *recordPath != "" {
+		recorder, err := client.NewRecorder(*recordPath)
+		if err != nil {
+			panic(err)
+		}
+		defer recorder.Close()
+		csgfClient.Recorder = recorder
+	}
+
+	err = csgfClient.Connect()
+	if err != nil {
 		panic(err)
 	}
 
 	csgfClient.StartListener()
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Default holds the metrics of the bot below, without the process and Go
+// runtime collectors of the global registry.
+var Default = prometheus.NewRegistry()
+
+var (
+	Reconnects    = newCounter("csgf_ws_reconnects_total", "Websocket reconnects.")
+	events        = newCounterVec("csgf_events_total", "Websocket events by channel.", "channel")
+	ParseFailures = newCounterVec("csgf_parse_failures_total", "Event parse failures by parser.", "parser")
+
+	BetsPlaced = newCounter("csgf_bets_placed_total", "Bets accepted by the site or the paper balance.")
+	BetsFailed = newCounter("csgf_bets_failed_total", "Bets that failed to be placed.")
+	betLatency = newHistogram("csgf_bet_latency_seconds", "Time from sending a bet to the site answer.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5})
+
+	ChatSent   = newCounter("csgf_chat_messages_sent_total", "Chat messages sent.")
+	ChatQueued = newGauge("csgf_chat_messages_queued", "Chat messages waiting for the rate limit.")
+
+	TransfersSent   = newCounter("csgf_transfers_sent_total", "Transfers sent.")
+	TransfersFailed = newCounter("csgf_transfers_failed_total", "Transfers that failed.")
+
+	balance = newGauge("csgf_balance", "Current balance, the virtual one in dry run.")
+
+	MathGamesStarted = newCounter("csgf_math_games_started_total", "Math chat games started.")
+	MathGamesSolved  = newCounter("csgf_math_games_solved_total", "Math chat games solved.")
+	MathGamesQueued  = newGauge("csgf_math_games_queued", "Math chat games waiting for the current one.")
+)
+
+func Event(channel string) {
+	events.WithLabelValues(labelValue(channel)).Inc()
+}
+
+func BetLatency(latency time.Duration) {
+	betLatency.Observe(latency.Seconds())
+}
+
+// SetBalance rounds the balance to cents, float32 amounts are not exact.
+func SetBalance(value float32) {
+	balance.Set(math.Round(float64(value)*100) / 100)
+}
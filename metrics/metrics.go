@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Serve exposes the default registry on /metrics of the address.
+func Serve(address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Default, promhttp.HandlerOpts{}))
+	return http.ListenAndServe(address, mux)
+}
+
+// labelValue keeps the label values short, channels with a user id become
+// one series per channel kind.
+func labelValue(value string) string {
+	if i := strings.IndexByte(value, '#'); i >= 0 {
+		return value[:i]
+	}
+	return value
+}
+
+func newCounter(name string, help string) prometheus.Counter {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: help})
+	Default.MustRegister(counter)
+	return counter
+}
+
+func newCounterVec(name string, help string, label string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, []string{label})
+	Default.MustRegister(counter)
+	return counter
+}
+
+func newGauge(name string, help string) prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+	Default.MustRegister(gauge)
+	return gauge
+}
+
+func newHistogram(name string, help string, buckets []float64) prometheus.Histogram {
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets})
+	Default.MustRegister(histogram)
+	return histogram
+}
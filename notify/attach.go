@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"github.com/Qwerty10291/csgf_bot/chat"
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+type winData struct {
+	GameId  int     `json:"game_id"`
+	Room    string  `json:"room"`
+	Stake   float32 `json:"stake"`
+	Payout  float32 `json:"payout"`
+	Profit  float32 `json:"profit"`
+	Virtual bool    `json:"virtual"`
+}
+
+type disconnectData struct {
+	Error string `json:"error"`
+}
+
+type balanceData struct {
+	Balance   float32 `json:"balance"`
+	Threshold float32 `json:"threshold"`
+}
+
+type payoutFailedData struct {
+	UserId   int     `json:"user_id"`
+	Username string  `json:"username"`
+	Amount   float32 `json:"amount"`
+	Error    string  `json:"error"`
+	// Uncertain payouts may have been sent and need a manual check
+	Uncertain bool `json:"uncertain"`
+}
+
+// Attach posts our wins, disconnects and a low balance of the client.
+func (n *Notifier) Attach(c *client.Client) {
+	c.AddSettlementHandler(func(settlement *client.Settlement) {
+		if !settlement.Won {
+			return
+		}
+		n.Notify(EventWin, winData{
+			GameId:  settlement.Game.Id,
+			Room:    settlement.Game.Room,
+			Stake:   settlement.Stake,
+			Payout:  settlement.Payout,
+			Profit:  settlement.Profit,
+			Virtual: settlement.Virtual,
+		})
+	})
+	c.AddDisconnectHandler(func(err error) {
+		n.Notify(EventDisconnect, disconnectData{Error: err.Error()})
+	})
+	if n.config.BalanceBelow > 0 {
+		c.AddBalanceUpdateHandler(func(event client.BalanceEvent) {
+			below := event.Balance < n.config.BalanceBelow
+			if below && !n.lowBalance {
+				n.Notify(EventLowBalance, balanceData{Balance: event.Balance, Threshold: n.config.BalanceBelow})
+			}
+			n.lowBalance = below
+		})
+	}
+}
+
+// AttachMathGame posts solved rounds and failed payouts of the chat game.
+func (n *Notifier) AttachMathGame(game *chat.MathChatGame) {
+	game.AddRoundHandler(func(round *chat.MathRound) {
+		n.Notify(EventMathFinished, round)
+	})
+	game.AddPayoutFailedHandler(func(failure *chat.MathPayoutFailure) {
+		n.Notify(EventPayoutFailed, payoutFailedData{
+			UserId:    failure.UserId,
+			Username:  failure.Username,
+			Amount:    failure.Amount,
+			Error:     failure.Err.Error(),
+			Uncertain: failure.Uncertain,
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventWin          EventType = "win"
+	EventMathFinished EventType = "math_finished"
+	EventPayoutFailed EventType = "payout_failed"
+	EventDisconnect   EventType = "disconnect"
+	EventLowBalance   EventType = "low_balance"
+)
+
+type Hook struct {
+	Url string `json:"url"`
+	// Secret signs the body with HMAC-SHA256 when set
+	Secret string `json:"secret"`
+	// Events selects the posted events, all of them when empty
+	Events []EventType `json:"events"`
+}
+
+func (h *Hook) wants(event EventType) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, wanted := range h.Events {
+		if wanted == event {
+			return true
+		}
+	}
+	return false
+}
+
+type Config struct {
+	Hooks []Hook
+	// Retries is the number of attempts after the first failed one
+	Retries int
+	// RetryDelay is doubled after every failed attempt
+	RetryDelay time.Duration
+	// DeadLetter is a json lines file for the events no hook accepted,
+	// they are dropped when empty
+	DeadLetter string
+	// BalanceBelow posts low_balance once the balance falls under it, off when zero
+	BalanceBelow float32
+}
+
+// LoadConfig reads the notifier config from a json file, retry_delay is a
+// duration string such as "2s".
+func LoadConfig(path string) (Config, error) {
+	var file struct {
+		Hooks        []Hook  `json:"hooks"`
+		Retries      int     `json:"retries"`
+		RetryDelay   string  `json:"retry_delay"`
+		DeadLetter   string  `json:"dead_letter"`
+		BalanceBelow float32 `json:"balance_below"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Config{}, fmt.Errorf("cannot parse notify config: %w", err)
+	}
+	config := Config{
+		Hooks:        file.Hooks,
+		Retries:      file.Retries,
+		RetryDelay:   time.Second,
+		DeadLetter:   file.DeadLetter,
+		BalanceBelow: file.BalanceBelow,
+	}
+	if file.RetryDelay != "" {
+		config.RetryDelay, err = time.ParseDuration(file.RetryDelay)
+		if err != nil {
+			return Config{}, fmt.Errorf("cannot parse retry_delay: %w", err)
+		}
+	}
+	return config, nil
+}
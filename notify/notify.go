@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// SignatureHeader carries "sha256=" and the hex HMAC of the body.
+const SignatureHeader = "X-Signature"
+
+type Event struct {
+	Type EventType   `json:"event"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// deadLetter is a line of the dead letter file.
+type deadLetter struct {
+	Url    string          `json:"url"`
+	Error  string          `json:"error"`
+	Failed time.Time       `json:"failed"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// Notifier posts events to the configured hooks. Events are queued, so the
+// callers never wait for the network.
+type Notifier struct {
+	config     Config
+	httpClient *http.Client
+	queue      chan *Event
+	done       chan struct{}
+	// lowBalance is set while the balance stays under the threshold
+	lowBalance bool
+}
+
+func NewNotifier(config Config) *Notifier {
+	n := &Notifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan *Event, 100),
+		done:       make(chan struct{}),
+	}
+	go n.deliver()
+	return n
+}
+
+func (n *Notifier) Notify(event EventType, data interface{}) {
+	select {
+	case n.queue <- &Event{Type: event, Time: time.Now(), Data: data}:
+	default:
+		fmt.Println("notify queue is full, dropping", event)
+	}
+}
+
+// Close posts the queued events and stops the notifier.
+func (n *Notifier) Close() {
+	close(n.queue)
+	<-n.done
+}
+
+func (n *Notifier) deliver() {
+	defer close(n.done)
+	for event := range n.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			fmt.Println("notify encode error", err)
+			continue
+		}
+		for i := range n.config.Hooks {
+			hook := &n.config.Hooks[i]
+			if !hook.wants(event.Type) {
+				continue
+			}
+			if err := n.post(hook, event.Type, body); err != nil {
+				fmt.Println("webhook", hook.Url, "failed", err)
+				n.writeDeadLetter(hook, body, err)
+			}
+		}
+	}
+}
+
+// post sends the body, retrying with a growing delay.
+func (n *Notifier) post(hook *Hook, event EventType, body []byte) error {
+	delay := n.config.RetryDelay
+	var err error
+	for attempt := 0; attempt <= n.config.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = n.send(hook, event, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (n *Notifier) send(hook *Hook, event EventType, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", string(event))
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, body))
+	}
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of the body, receivers compare it with
+// the signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) writeDeadLetter(hook *Hook, body []byte, failure error) {
+	if n.config.DeadLetter == "" {
+		return
+	}
+	line, err := json.Marshal(deadLetter{Url: hook.Url, Error: failure.Error(), Failed: time.Now(), Event: body})
+	if err != nil {
+		fmt.Println("dead letter encode error", err)
+		return
+	}
+	file, err := os.OpenFile(n.config.DeadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Println("dead letter open error", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		fmt.Println("dead letter write error", err)
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver answers the first failures requests with 500 and keeps the
+// requests it got.
+type receiver struct {
+	failures int
+
+	mu     sync.Mutex
+	bodies [][]byte
+	heads  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	r.heads = append(r.heads, req.Header.Clone())
+	if len(r.bodies) <= r.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func testConfig(url string, deadPath string) Config {
+	return Config{
+		Hooks:      []Hook{{Url: url, Secret: "secret"}},
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		DeadLetter: deadPath,
+	}
+}
+
+func TestNotifierSignsAndRetries(t *testing.T) {
+	r := &receiver{failures: 1}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	deadPath := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	n := NewNotifier(testConfig(server.URL, deadPath))
+	n.Notify(EventDisconnect, disconnectData{Error: "eof"})
+	n.Close()
+
+	if len(r.bodies) != 2 {
+		t.Fatalf("got %d requests, want 2", len(r.bodies))
+	}
+	for i, body := range r.bodies {
+		want := "sha256=" + Sign("secret", body)
+		if got := r.heads[i].Get(SignatureHeader); got != want {
+			t.Errorf("request %d signature %q, want %q", i, got, want)
+		}
+		if got := r.heads[i].Get("X-Event"); got != string(EventDisconnect) {
+			t.Errorf("request %d event %q", i, got)
+		}
+	}
+	var event struct {
+		Type EventType      `json:"event"`
+		Data disconnectData `json:"data"`
+	}
+	if err := json.Unmarshal(r.bodies[1], &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventDisconnect || event.Data.Error != "eof" {
+		t.Errorf("unexpected body %s", r.bodies[1])
+	}
+	if _, err := os.Stat(deadPath); !os.IsNotExist(err) {
+		t.Errorf("dead letter written for a delivered event")
+	}
+}
+
+func TestNotifierDeadLetter(t *testing.T) {
+	r := &receiver{failures: 100}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	deadPath := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	n := NewNotifier(testConfig(server.URL, deadPath))
+	n.Notify(EventLowBalance, balanceData{Balance: 5, Threshold: 10})
+	n.Close()
+
+	if len(r.bodies) != 3 {
+		t.Fatalf("got %d requests, want the first one and 2 retries", len(r.bodies))
+	}
+	data, err := os.ReadFile(deadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d dead letter lines, want 1", len(lines))
+	}
+	var letter deadLetter
+	if err := json.Unmarshal([]byte(lines[0]), &letter); err != nil {
+		t.Fatal(err)
+	}
+	if letter.Url != server.URL || !strings.Contains(letter.Error, "500") {
+		t.Errorf("unexpected dead letter %s", lines[0])
+	}
+	if string(letter.Event) != string(r.bodies[0]) {
+		t.Errorf("dead letter event %s, want the posted body %s", letter.Event, r.bodies[0])
+	}
+}
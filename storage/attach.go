@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// Attach stores every finished game with its bets, our bets, settlements,
+// balance changes, chat messages and received transfers of the client.
+func (s *DB) Attach(c *client.Client) {
+	c.AddGameUpdateHandler(func(game *client.Game, reason client.GameUpdateReason) {
+		if reason != client.GameEnd {
+			return
+		}
+		if err := s.SaveGame(game); err != nil {
+			fmt.Println("storage: save game error", err)
+		}
+	})
+	c.AddBetConfirmHandler(func(confirmation *client.BetConfirmation) {
+		if err := s.SaveOwnBet(confirmation); err != nil {
+			fmt.Println("storage: save bet error", err)
+		}
+	})
+	c.AddSettlementHandler(func(settlement *client.Settlement) {
+		if err := s.SaveSettlement(settlement, settlement.Game.Finished); err != nil {
+			fmt.Println("storage: save settlement error", err)
+		}
+	})
+	c.AddChatUpdateHandler(func(event *client.ChatEvent) {
+		if err := s.SaveChatMessage(event); err != nil {
+			fmt.Println("storage: save chat message error", err)
+		}
+	})
+	c.AddTransferEventHandler(func(event *client.NotifyEventTransfer) {
+		if err := s.SaveTransfer(event); err != nil {
+			fmt.Println("storage: save transfer error", err)
+		}
+	})
+	c.AddBalanceUpdateHandler(func(event client.BalanceEvent) {
+		if err := s.SaveBalance(time.Now(), event.Balance); err != nil {
+			fmt.Println("storage: save balance error", err)
+		}
+	})
+}
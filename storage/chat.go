@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+type ChatRecord struct {
+	UserId   int
+	Username string
+	Message  string
+	Received time.Time
+}
+
+// TransferRecord is a transfer received by our account.
+type TransferRecord struct {
+	FromUser string
+	Amount   Money
+	Received time.Time
+}
+
+func (s *DB) SaveChatMessage(event *client.ChatEvent) error {
+	_, err := s.db.Exec(`INSERT INTO chat_messages (user_id, username, message, received_at) VALUES (?, ?, ?, ?)`,
+		event.UserId, event.Username, event.Message, unixMilli(event.Time))
+	return err
+}
+
+func (s *DB) SaveTransfer(event *client.NotifyEventTransfer) error {
+	_, err := s.db.Exec(`INSERT INTO transfers (from_user, amount, received_at) VALUES (?, ?, ?)`,
+		event.FromUser, MoneyFromFloat(event.Amount), unixMilli(event.Time))
+	return err
+}
+
+func (s *DB) ChatMessages(filter Filter) ([]ChatRecord, error) {
+	where, args := filter.where("received_at", "", "user_id")
+	rows, err := s.db.Query(`SELECT user_id, username, message, received_at FROM chat_messages`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []ChatRecord{}
+	for rows.Next() {
+		var message ChatRecord
+		var received int64
+		if err := rows.Scan(&message.UserId, &message.Username, &message.Message, &received); err != nil {
+			return nil, err
+		}
+		message.Received = fromUnixMilli(received)
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *DB) Transfers(filter Filter) ([]TransferRecord, error) {
+	where, args := filter.where("received_at", "", "")
+	rows, err := s.db.Query(`SELECT from_user, amount, received_at FROM transfers`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	transfers := []TransferRecord{}
+	for rows.Next() {
+		var transfer TransferRecord
+		var received int64
+		if err := rows.Scan(&transfer.FromUser, &transfer.Amount, &received); err != nil {
+			return nil, err
+		}
+		transfer.Received = fromUnixMilli(received)
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+type GameRecord struct {
+	Id         int
+	RoomId     int
+	Room       string
+	Created    time.Time
+	Finished   time.Time
+	Bank       Money
+	Players    int
+	WinnerId   int
+	WinnerName string
+	Ticket     int
+	Commission Money
+	Hash       string
+	Seed       string
+	// Won is set when we were the winner
+	Won bool
+}
+
+type BetRecord struct {
+	GameId     int
+	UserId     int
+	Username   string
+	Amount     Money
+	Placed     time.Time
+	TicketFrom int
+	TicketTo   int
+	Virtual    bool
+}
+
+type OwnBetRecord struct {
+	GameId    int
+	Amount    Money
+	Placed    time.Time
+	Confirmed bool
+	Latency   time.Duration
+}
+
+type SettlementRecord struct {
+	GameId  int
+	Stake   Money
+	Payout  Money
+	Profit  Money
+	Won     bool
+	Virtual bool
+	Settled time.Time
+}
+
+type BalanceRecord struct {
+	Taken   time.Time
+	Balance Money
+}
+
+// Filter selects records by time, zero values are not applied. RoomId and
+// UserId only apply to games and bets.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	RoomId int
+	UserId int
+	Limit  int
+}
+
+// where builds the condition for the given time column.
+func (f Filter) where(timeColumn string, roomColumn string, userColumn string) (string, []interface{}) {
+	return f.whereOrdered(timeColumn, roomColumn, userColumn, timeColumn)
+}
+
+func (f Filter) whereOrdered(timeColumn string, roomColumn string, userColumn string, order string) (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if !f.From.IsZero() {
+		conditions = append(conditions, timeColumn+" >= ?")
+		args = append(args, unixMilli(f.From))
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, timeColumn+" < ?")
+		args = append(args, unixMilli(f.To))
+	}
+	if f.RoomId != 0 && roomColumn != "" {
+		conditions = append(conditions, roomColumn+" = ?")
+		args = append(args, f.RoomId)
+	}
+	if f.UserId != 0 && userColumn != "" {
+		conditions = append(conditions, userColumn+" = ?")
+		args = append(args, f.UserId)
+	}
+	query := " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY " + order
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+	return query, args
+}
+
+// SaveGame stores a finished game with all its bets, saving a game again
+// replaces it.
+func (s *DB) SaveGame(game *client.Game) error {
+	record := GameRecord{
+		Id:       game.Id,
+		RoomId:   game.RoomId,
+		Room:     game.Room,
+		Created:  game.Created,
+		Finished: game.Finished,
+		Bank:     MoneyFromFloat(game.Bank - game.VirtualStake),
+		Won:      game.Won,
+	}
+	if result := game.Result; result != nil {
+		record.WinnerId = result.WinnerId
+		record.WinnerName = result.WinnerName
+		record.Ticket = result.Ticket
+		record.Commission = MoneyFromFloat(result.Commission)
+		record.Hash = result.Hash
+		record.Seed = result.Seed
+	}
+	bets := []BetRecord{}
+	players := map[int]bool{}
+	for _, bet := range game.Bets {
+		if !bet.Virtual {
+			players[bet.UserId] = true
+		}
+		bets = append(bets, BetRecord{
+			GameId:     game.Id,
+			UserId:     bet.UserId,
+			Username:   bet.Username,
+			Amount:     MoneyFromFloat(bet.Amount),
+			Placed:     bet.Time,
+			TicketFrom: bet.TicketFrom,
+			TicketTo:   bet.TicketTo,
+			Virtual:    bet.Virtual,
+		})
+	}
+	// our paper bets are stored but are not part of the real game
+	record.Players = len(players)
+	return s.UpsertGame(record, bets)
+}
+
+// UpsertGame inserts or replaces a game and its bets in one transaction.
+func (s *DB) UpsertGame(game GameRecord, bets []BetRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO games (id, room_id, room, created_at, finished_at, bank, players,
+			winner_id, winner_name, ticket, commission, hash, seed, won)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET room_id = excluded.room_id, room = excluded.room,
+			created_at = excluded.created_at, finished_at = excluded.finished_at, bank = excluded.bank,
+			players = excluded.players, winner_id = excluded.winner_id, winner_name = excluded.winner_name,
+			ticket = excluded.ticket, commission = excluded.commission, hash = excluded.hash,
+			seed = excluded.seed, won = excluded.won`,
+		game.Id, game.RoomId, game.Room, unixMilli(game.Created), unixMilli(game.Finished), game.Bank, game.Players,
+		game.WinnerId, game.WinnerName, game.Ticket, game.Commission, game.Hash, game.Seed, game.Won)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM bets WHERE game_id = ?`, game.Id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, bet := range bets {
+		_, err := tx.Exec(`INSERT INTO bets (game_id, user_id, username, amount, placed_at, ticket_from, ticket_to, virtual)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			game.Id, bet.UserId, bet.Username, bet.Amount, unixMilli(bet.Placed), bet.TicketFrom, bet.TicketTo, bet.Virtual)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *DB) SaveOwnBet(confirmation *client.BetConfirmation) error {
+	_, err := s.db.Exec(`INSERT INTO own_bets (game_id, amount, placed_at, confirmed, latency_ms) VALUES (?, ?, ?, ?, ?)`,
+		confirmation.Game.Id, MoneyFromFloat(confirmation.Amount), unixMilli(confirmation.Sent),
+		confirmation.Confirmed, confirmation.Latency.Milliseconds())
+	return err
+}
+
+func (s *DB) SaveSettlement(settlement *client.Settlement, settled time.Time) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO settlements (game_id, stake, payout, profit, won, virtual, settled_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		settlement.Game.Id, MoneyFromFloat(settlement.Stake), MoneyFromFloat(settlement.Payout),
+		MoneyFromFloat(settlement.Profit), settlement.Won, settlement.Virtual, unixMilli(settled))
+	return err
+}
+
+func (s *DB) SaveBalance(taken time.Time, balance float32) error {
+	_, err := s.db.Exec(`INSERT INTO balances (taken_at, balance) VALUES (?, ?)`, unixMilli(taken), MoneyFromFloat(balance))
+	return err
+}
+
+func (s *DB) Games(filter Filter) ([]GameRecord, error) {
+	where, args := filter.where("finished_at", "room_id", "")
+	rows, err := s.db.Query(`SELECT id, room_id, room, created_at, finished_at, bank, players,
+		winner_id, winner_name, ticket, commission, hash, seed, won FROM games`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	games := []GameRecord{}
+	for rows.Next() {
+		var game GameRecord
+		var created, finished int64
+		err := rows.Scan(&game.Id, &game.RoomId, &game.Room, &created, &finished, &game.Bank, &game.Players,
+			&game.WinnerId, &game.WinnerName, &game.Ticket, &game.Commission, &game.Hash, &game.Seed, &game.Won)
+		if err != nil {
+			return nil, err
+		}
+		game.Created = fromUnixMilli(created)
+		game.Finished = fromUnixMilli(finished)
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// Bets returns the bets of the games finished in the selected time range,
+// the same games Games returns, in order.
+func (s *DB) Bets(filter Filter) ([]BetRecord, error) {
+	where, args := filter.whereOrdered("games.finished_at", "games.room_id", "bets.user_id", "games.finished_at, bets.id")
+	return s.queryBets(`SELECT bets.game_id, bets.user_id, bets.username, bets.amount, bets.placed_at,
+		bets.ticket_from, bets.ticket_to, bets.virtual FROM bets JOIN games ON games.id = bets.game_id`+where, args...)
+}
+
+func (s *DB) GameBets(gameId int) ([]BetRecord, error) {
+	return s.queryBets(`SELECT game_id, user_id, username, amount, placed_at, ticket_from, ticket_to, virtual
+		FROM bets WHERE game_id = ? ORDER BY id`, gameId)
+}
+
+func (s *DB) queryBets(query string, args ...interface{}) ([]BetRecord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	bets := []BetRecord{}
+	for rows.Next() {
+		var bet BetRecord
+		var placed int64
+		err := rows.Scan(&bet.GameId, &bet.UserId, &bet.Username, &bet.Amount, &placed, &bet.TicketFrom, &bet.TicketTo, &bet.Virtual)
+		if err != nil {
+			return nil, err
+		}
+		bet.Placed = fromUnixMilli(placed)
+		bets = append(bets, bet)
+	}
+	return bets, rows.Err()
+}
+
+func (s *DB) OwnBets(filter Filter) ([]OwnBetRecord, error) {
+	where, args := filter.where("placed_at", "", "")
+	rows, err := s.db.Query(`SELECT game_id, amount, placed_at, confirmed, latency_ms FROM own_bets`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	bets := []OwnBetRecord{}
+	for rows.Next() {
+		var bet OwnBetRecord
+		var placed, latency int64
+		if err := rows.Scan(&bet.GameId, &bet.Amount, &placed, &bet.Confirmed, &latency); err != nil {
+			return nil, err
+		}
+		bet.Placed = fromUnixMilli(placed)
+		bet.Latency = time.Duration(latency) * time.Millisecond
+		bets = append(bets, bet)
+	}
+	return bets, rows.Err()
+}
+
+func (s *DB) Settlements(filter Filter) ([]SettlementRecord, error) {
+	where, args := filter.where("settled_at", "", "")
+	rows, err := s.db.Query(`SELECT game_id, stake, payout, profit, won, virtual, settled_at FROM settlements`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	settlements := []SettlementRecord{}
+	for rows.Next() {
+		var settlement SettlementRecord
+		var settled int64
+		err := rows.Scan(&settlement.GameId, &settlement.Stake, &settlement.Payout, &settlement.Profit,
+			&settlement.Won, &settlement.Virtual, &settled)
+		if err != nil {
+			return nil, err
+		}
+		settlement.Settled = fromUnixMilli(settled)
+		settlements = append(settlements, settlement)
+	}
+	return settlements, rows.Err()
+}
+
+func (s *DB) Balances(filter Filter) ([]BalanceRecord, error) {
+	where, args := filter.where("taken_at", "", "")
+	rows, err := s.db.Query(`SELECT taken_at, balance FROM balances`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []BalanceRecord{}
+	for rows.Next() {
+		var balance BalanceRecord
+		var taken int64
+		if err := rows.Scan(&taken, &balance.Balance); err != nil {
+			return nil, err
+		}
+		balance.Taken = fromUnixMilli(taken)
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// migrations are applied in order, a new schema change is a new entry.
+var migrations = []string{
+	`CREATE TABLE games (
+		id INTEGER PRIMARY KEY,
+		room_id INTEGER NOT NULL,
+		room TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		finished_at INTEGER NOT NULL,
+		bank INTEGER NOT NULL,
+		players INTEGER NOT NULL,
+		winner_id INTEGER NOT NULL DEFAULT 0,
+		winner_name TEXT NOT NULL DEFAULT '',
+		ticket INTEGER NOT NULL DEFAULT 0,
+		commission INTEGER NOT NULL DEFAULT 0,
+		hash TEXT NOT NULL DEFAULT '',
+		seed TEXT NOT NULL DEFAULT '',
+		won INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX games_finished_at ON games (finished_at);
+	CREATE TABLE bets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		game_id INTEGER NOT NULL REFERENCES games (id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		amount INTEGER NOT NULL,
+		placed_at INTEGER NOT NULL,
+		ticket_from INTEGER NOT NULL DEFAULT 0,
+		ticket_to INTEGER NOT NULL DEFAULT 0,
+		virtual INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX bets_game_id ON bets (game_id);
+	CREATE INDEX bets_user_id ON bets (user_id);
+	CREATE TABLE own_bets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		game_id INTEGER NOT NULL,
+		amount INTEGER NOT NULL,
+		placed_at INTEGER NOT NULL,
+		confirmed INTEGER NOT NULL,
+		latency_ms INTEGER NOT NULL
+	);
+	CREATE TABLE settlements (
+		game_id INTEGER PRIMARY KEY,
+		stake INTEGER NOT NULL,
+		payout INTEGER NOT NULL,
+		profit INTEGER NOT NULL,
+		won INTEGER NOT NULL,
+		virtual INTEGER NOT NULL,
+		settled_at INTEGER NOT NULL
+	);
+	CREATE TABLE balances (
+		taken_at INTEGER NOT NULL,
+		balance INTEGER NOT NULL
+	);`,
+	`CREATE TABLE chat_messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL,
+		message TEXT NOT NULL,
+		received_at INTEGER NOT NULL
+	);
+	CREATE INDEX chat_messages_received_at ON chat_messages (received_at);
+	CREATE TABLE transfers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_user TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		received_at INTEGER NOT NULL
+	);`,
+	`CREATE TABLE import_progress (
+		name TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);`,
+}
+
+func (s *DB) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
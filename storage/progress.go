@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"database/sql"
+)
+
+// Progress returns the saved position of a long running job, zero when the
+// job has not started yet.
+func (s *DB) Progress(name string) (int, error) {
+	var value int
+	err := s.db.QueryRow(`SELECT value FROM import_progress WHERE name = ?`, name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return value, err
+}
+
+func (s *DB) SetProgress(name string, value int) error {
+	_, err := s.db.Exec(`INSERT INTO import_progress (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value`, name, value)
+	return err
+}
+
+// HasGame reports whether the game is already stored.
+func (s *DB) HasGame(id int) (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM games WHERE id = ?`, id).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Money is an amount in cents, so sums stay exact.
+type Money int64
+
+func MoneyFromFloat(amount float32) Money {
+	return Money(math.Round(float64(amount) * 100))
+}
+
+func (m Money) Float() float32 {
+	return float32(m) / 100
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database and applies pending migrations.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows a single writer, one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	storage := &DB{db: db}
+	if err := storage.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (s *DB) Close() error {
+	return s.db.Close()
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromUnixMilli(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File keeps the values in values.json and every log in its own json lines
+// file inside a directory.
+type File struct {
+	dir string
+
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+}
+
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f := &File{dir: dir, values: map[string]json.RawMessage{}}
+	data, err := os.ReadFile(f.valuesPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &f.values); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", f.valuesPath(), err)
+		}
+	}
+	return f, nil
+}
+
+func (f *File) valuesPath() string {
+	return filepath.Join(f.dir, "values.json")
+}
+
+func (f *File) logPath(log string) string {
+	return filepath.Join(f.dir, url.PathEscape(log)+".jsonl")
+}
+
+func (f *File) Get(key string, value interface{}) (bool, error) {
+	f.mu.Lock()
+	data, ok := f.values[key]
+	f.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+func (f *File) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = data
+	return f.save()
+}
+
+func (f *File) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.values, key)
+	return f.save()
+}
+
+// save writes the values to a temporary file first, so a crash never leaves
+// a truncated values.json behind.
+func (f *File) save() error {
+	data, err := json.MarshalIndent(f.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.valuesPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.valuesPath())
+}
+
+func (f *File) Append(log string, entry interface{}) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.logPath(log), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *File) ReadLog(log string, fn func(entry json.RawMessage) error) error {
+	file, err := os.Open(f.logPath(log))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := append(json.RawMessage{}, scanner.Bytes()...)
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (f *File) Close() error {
+	return nil
+}
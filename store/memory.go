@@ -0,0 +1,74 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+type Memory struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	logs   map[string][][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		values: map[string][]byte{},
+		logs:   map[string][][]byte{},
+	}
+}
+
+func (m *Memory) Get(key string, value interface{}) (bool, error) {
+	m.mu.Lock()
+	data, ok := m.values[key]
+	m.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+func (m *Memory) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = data
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *Memory) Append(log string, entry interface{}) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs[log] = append(m.logs[log], data)
+	return nil
+}
+
+func (m *Memory) ReadLog(log string, fn func(entry json.RawMessage) error) error {
+	m.mu.Lock()
+	entries := append([][]byte{}, m.logs[log]...)
+	m.mu.Unlock()
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
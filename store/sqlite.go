@@ -0,0 +1,101 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLite struct {
+	db *sql.DB
+}
+
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS kv (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+		CREATE TABLE IF NOT EXISTS logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			log TEXT NOT NULL,
+			entry TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS logs_log ON logs (log, id);`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Get(key string, value interface{}) (bool, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT value FROM kv WHERE key = ?`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), value)
+}
+
+func (s *SQLite) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO kv (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, string(data))
+	return err
+}
+
+func (s *SQLite) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM kv WHERE key = ?`, key)
+	return err
+}
+
+func (s *SQLite) Append(log string, entry interface{}) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO logs (log, entry, created_at) VALUES (?, ?, ?)`, log, string(data), time.Now().UnixMilli())
+	return err
+}
+
+func (s *SQLite) ReadLog(log string, fn func(entry json.RawMessage) error) error {
+	rows, err := s.db.Query(`SELECT entry FROM logs WHERE log = ? ORDER BY id`, log)
+	if err != nil {
+		return err
+	}
+	// entries are collected first, fn may write to the store
+	entries := []json.RawMessage{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, json.RawMessage(data))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Store keeps plugin state: json encoded values by key and append-only logs.
+type Store interface {
+	// Get decodes the value of key into value and reports whether it exists.
+	Get(key string, value interface{}) (bool, error)
+	Set(key string, value interface{}) error
+	Delete(key string) error
+	// Append adds an entry to the end of a log.
+	Append(log string, entry interface{}) error
+	// ReadLog calls fn for every entry of a log in order.
+	ReadLog(log string, fn func(entry json.RawMessage) error) error
+	Close() error
+}
+
+// Open picks the backend by path: memory when empty, sqlite for a .db file
+// and json files in a directory otherwise.
+func Open(path string) (Store, error) {
+	switch {
+	case path == "":
+		return NewMemory(), nil
+	case strings.HasSuffix(path, ".db"):
+		return NewSQLite(path)
+	default:
+		return NewFile(path)
+	}
+}
+
+// namespace prefixes every key and log name, so plugins can share a store.
+type namespace struct {
+	Store
+	prefix string
+}
+
+func Namespace(s Store, prefix string) Store {
+	return &namespace{Store: s, prefix: prefix + "/"}
+}
+
+func (n *namespace) Get(key string, value interface{}) (bool, error) {
+	return n.Store.Get(n.prefix+key, value)
+}
+
+func (n *namespace) Set(key string, value interface{}) error {
+	return n.Store.Set(n.prefix+key, value)
+}
+
+func (n *namespace) Delete(key string) error {
+	return n.Store.Delete(n.prefix + key)
+}
+
+func (n *namespace) Append(log string, entry interface{}) error {
+	return n.Store.Append(n.prefix+log, entry)
+}
+
+func (n *namespace) ReadLog(log string, fn func(entry json.RawMessage) error) error {
+	return n.Store.ReadLog(n.prefix+log, fn)
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type entry struct {
+	Name   string
+	Amount float32
+}
+
+// backends open a store of every kind in a directory, persistent ones
+// keep their data when opened again.
+var backends = []struct {
+	name       string
+	open       func(dir string) (Store, error)
+	persistent bool
+}{
+	{name: "memory", open: func(string) (Store, error) { return NewMemory(), nil }},
+	{name: "file", open: func(dir string) (Store, error) { return NewFile(filepath.Join(dir, "state")) }, persistent: true},
+	{name: "sqlite", open: func(dir string) (Store, error) { return NewSQLite(filepath.Join(dir, "state.db")) }, persistent: true},
+}
+
+func readLog(t *testing.T, s Store, log string) []entry {
+	t.Helper()
+	entries := []entry{}
+	err := s.ReadLog(log, func(data json.RawMessage) error {
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := backend.open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var missing entry
+			if ok, err := s.Get("missing", &missing); ok || err != nil {
+				t.Fatalf("missing key: ok %v, err %v", ok, err)
+			}
+			want := entry{Name: "pete", Amount: 1.5}
+			if err := s.Set("value", want); err != nil {
+				t.Fatal(err)
+			}
+			var got entry
+			if ok, err := s.Get("value", &got); !ok || err != nil || got != want {
+				t.Fatalf("got %v (ok %v, err %v), want %v", got, ok, err, want)
+			}
+
+			logged := []entry{{Name: "a", Amount: 1}, {Name: "b", Amount: 2}, {Name: "c", Amount: 3}}
+			for _, e := range logged {
+				if err := s.Append("rounds", e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Append("other", entry{Name: "x"}); err != nil {
+				t.Fatal(err)
+			}
+			if entries := readLog(t, s, "rounds"); !reflect.DeepEqual(entries, logged) {
+				t.Fatalf("log %v, want %v", entries, logged)
+			}
+			if entries := readLog(t, s, "empty"); len(entries) != 0 {
+				t.Fatalf("unknown log has %d entries", len(entries))
+			}
+
+			if err := s.Delete("value"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _ := s.Get("value", &got); ok {
+				t.Fatal("deleted key still exists")
+			}
+			if err := s.Set("kept", want); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !backend.persistent {
+				return
+			}
+
+			s, err = backend.open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			got = entry{}
+			if ok, err := s.Get("kept", &got); !ok || err != nil || got != want {
+				t.Fatalf("after reopen got %v (ok %v, err %v), want %v", got, ok, err, want)
+			}
+			if entries := readLog(t, s, "rounds"); !reflect.DeepEqual(entries, logged) {
+				t.Fatalf("after reopen log %v, want %v", entries, logged)
+			}
+		})
+	}
+}
+
+func TestOpenPicksBackend(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		want Store
+	}{
+		{path: "", want: &Memory{}},
+		{path: filepath.Join(dir, "state.db"), want: &SQLite{}},
+		{path: filepath.Join(dir, "state"), want: &File{}},
+	}
+	for _, test := range tests {
+		s, err := Open(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(s) != reflect.TypeOf(test.want) {
+			t.Errorf("path %q opened %T, want %T", test.path, s, test.want)
+		}
+		s.Close()
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	s := NewMemory()
+	first, second := Namespace(s, "first"), Namespace(s, "second")
+	if err := first.Set("key", entry{Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Append("log", entry{Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got entry
+	if ok, _ := second.Get("key", &got); ok {
+		t.Error("namespaces share a key")
+	}
+	if entries := readLog(t, second, "log"); len(entries) != 0 {
+		t.Error("namespaces share a log")
+	}
+	if ok, _ := s.Get("first/key", &got); !ok || got.Name != "first" {
+		t.Errorf("prefixed key not found, got %v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// TargetPercent keeps our share of the bank at Percent.
+type TargetPercent struct {
+	Percent float32
+}
+
+func (s *TargetPercent) Name() string {
+	return fmt.Sprintf("target_percent(%.2f)", s.Percent)
+}
+
+func (s *TargetPercent) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	if reason == client.GameEnd || game.GetCurrentPercent() >= s.Percent {
+		return nil
+	}
+	plan := game.PlanBets(s.Percent, balance)
+	decisions := []Decision{}
+	for _, bet := range plan.Bets {
+		decisions = append(decisions, Decision{Amount: bet, Reason: "below target percent"})
+	}
+	if plan.Reason != "" {
+		fmt.Printf("%s: game %d chance %.3f, %s\n", s.Name(), game.Id, plan.Chance, plan.Reason)
+	}
+	return decisions
+}
+
+// FixedStake bets Amount once in every game.
+type FixedStake struct {
+	Amount float32
+}
+
+func (s *FixedStake) Name() string {
+	return fmt.Sprintf("fixed_stake(%.2f)", s.Amount)
+}
+
+func (s *FixedStake) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	if reason == client.GameEnd || game.BetNow > 0 {
+		return nil
+	}
+	amount := clampStake(game, s.Amount, balance)
+	if amount <= 0 {
+		return nil
+	}
+	return []Decision{{Amount: amount, Reason: "fixed stake"}}
+}
+
+// BankThreshold enters a game with Stake once its bank reaches MinBank.
+type BankThreshold struct {
+	MinBank float32
+	Stake   float32
+}
+
+func (s *BankThreshold) Name() string {
+	return fmt.Sprintf("bank_threshold(%.2f, %.2f)", s.MinBank, s.Stake)
+}
+
+func (s *BankThreshold) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	if reason == client.GameEnd || game.BetNow > 0 || game.Bank < s.MinBank {
+		return nil
+	}
+	amount := clampStake(game, s.Stake, balance)
+	if amount <= 0 {
+		return nil
+	}
+	return []Decision{{Amount: amount, Reason: "bank reached threshold"}}
+}
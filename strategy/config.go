@@ -0,0 +1,92 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Config struct {
+	// Name selects a built-in strategy
+	Name string `json:"name"`
+	// Rooms limits the strategy to the listed room names, all rooms when empty
+	Rooms []string `json:"rooms"`
+	// BetBefore delays the bets until this many seconds before the countdown ends
+	BetBefore float64            `json:"bet_before"`
+	Params    map[string]float64 `json:"params"`
+	// Watch maps user ids to the scale of their copied bets for the copy strategy
+	Watch map[int]float64 `json:"watch"`
+	// Rules are the expressions of the rules strategy
+	Rules []Rule `json:"rules"`
+}
+
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("cannot parse strategy config: %w", err)
+	}
+	return config, nil
+}
+
+func FromConfig(config Config) (Strategy, error) {
+	param := func(name string) (float32, error) {
+		value, ok := config.Params[name]
+		if !ok {
+			return 0, fmt.Errorf("strategy %s requires param %s", config.Name, name)
+		}
+		return float32(value), nil
+	}
+
+	var s Strategy
+	switch config.Name {
+	case "target_percent":
+		percent, err := param("percent")
+		if err != nil {
+			return nil, err
+		}
+		s = &TargetPercent{Percent: percent}
+	case "fixed_stake":
+		amount, err := param("amount")
+		if err != nil {
+			return nil, err
+		}
+		s = &FixedStake{Amount: amount}
+	case "bank_threshold":
+		minBank, err := param("min_bank")
+		if err != nil {
+			return nil, err
+		}
+		stake, err := param("stake")
+		if err != nil {
+			return nil, err
+		}
+		s = &BankThreshold{MinBank: minBank, Stake: stake}
+	case "copy":
+		if len(config.Watch) == 0 {
+			return nil, fmt.Errorf("strategy copy requires watched users")
+		}
+		copyBets := &CopyBets{Users: map[int]float32{}, Cap: float32(config.Params["cap"])}
+		for userId, scale := range config.Watch {
+			copyBets.Users[userId] = float32(scale)
+		}
+		s = copyBets
+	case "rules":
+		rules, err := NewRules(config.Rules)
+		if err != nil {
+			return nil, err
+		}
+		s = rules
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", config.Name)
+	}
+	s = OnlyRooms(s, config.Rooms...)
+	if config.BetBefore > 0 {
+		s = LastSeconds(s, time.Duration(config.BetBefore*float64(time.Second)))
+	}
+	return s, nil
+}
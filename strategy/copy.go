@@ -0,0 +1,44 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// CopyBets mirrors the bets of watched players in the same game.
+type CopyBets struct {
+	// Users maps a watched user id to the share of their bet we place
+	Users map[int]float32
+	// Cap limits a single copied bet, the room MaxBet applies anyway
+	Cap float32
+}
+
+func (s *CopyBets) Name() string {
+	return fmt.Sprintf("copy(%d users)", len(s.Users))
+}
+
+func (s *CopyBets) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	if reason != client.GameBet {
+		return nil
+	}
+	bet := game.LastBet()
+	if bet == nil || bet.Virtual {
+		return nil
+	}
+	scale, ok := s.Users[bet.UserId]
+	if !ok {
+		return nil
+	}
+
+	amount := float32(math.Floor(float64(bet.Amount*scale)*100) / 100)
+	if s.Cap > 0 && amount > s.Cap {
+		amount = s.Cap
+	}
+	amount = clampStake(game, amount, balance)
+	if amount <= 0 {
+		return nil
+	}
+	return []Decision{{Amount: amount, Reason: fmt.Sprintf("copy of user %d bet %.2f", bet.UserId, bet.Amount)}}
+}
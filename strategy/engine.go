@@ -0,0 +1,81 @@
+package strategy
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// Engine runs a strategy on the client game updates and places its bets.
+type Engine struct {
+	client   *client.Client
+	strategy Strategy
+
+	mu        sync.Mutex
+	gameLocks map[int]*sync.Mutex
+}
+
+func NewEngine(c *client.Client, s Strategy) *Engine {
+	engine := &Engine{
+		client:    c,
+		strategy:  s,
+		gameLocks: map[int]*sync.Mutex{},
+	}
+	c.AddGameUpdateHandler(engine.gameUpdateHandler)
+	return engine
+}
+
+func (e *Engine) gameUpdateHandler(game *client.Game, reason client.GameUpdateReason) {
+	lock := e.gameLock(game.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	decisions := e.strategy.Decide(game, reason, e.client.Balance)
+	if reason == client.GameEnd {
+		e.mu.Lock()
+		delete(e.gameLocks, game.Id)
+		e.mu.Unlock()
+		return
+	}
+	if timed, ok := e.strategy.(Timed); ok {
+		if reason == client.GameNew {
+			e.client.ScheduleBefore(game, timed.BetBefore(), e.scheduledHandler)
+		}
+		return
+	}
+	e.execute(game, decisions)
+}
+
+func (e *Engine) scheduledHandler(game *client.Game) {
+	lock := e.gameLock(game.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	e.execute(game, e.strategy.Decide(game, client.GameTime, e.client.Balance))
+}
+
+// execute places decisions in order and stops at the first failed bet.
+func (e *Engine) execute(game *client.Game, decisions []Decision) {
+	for _, decision := range decisions {
+		if decision.Amount <= 0 {
+			continue
+		}
+		fmt.Printf("%s: bet %.2f in game %d (%s)\n", e.strategy.Name(), decision.Amount, game.Id, decision.Reason)
+		if err := e.client.MakeBet(game, decision.Amount); err != nil {
+			fmt.Println(e.strategy.Name(), "bet failed", err)
+			return
+		}
+	}
+}
+
+func (e *Engine) gameLock(gameId int) *sync.Mutex {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	lock, ok := e.gameLocks[gameId]
+	if !ok {
+		lock = &sync.Mutex{}
+		e.gameLocks[gameId] = lock
+	}
+	return lock
+}
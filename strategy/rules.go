@@ -0,0 +1,130 @@
+package strategy
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Knetic/govaluate"
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+// Rule bets the value of Bet when the When expression holds. Both are
+// govaluate expressions over bank, time_left, players, my_chance, my_stake,
+// room, room_id and balance; Bet can also call bet_for_percent(p) and
+// min_bet(), max_bet().
+type Rule struct {
+	When string `json:"when"`
+	Bet  string `json:"bet"`
+}
+
+type compiledRule struct {
+	source Rule
+	when   *govaluate.EvaluableExpression
+	bet    *govaluate.EvaluableExpression
+}
+
+// Rules applies the first matching rule on every game update. A rule bets
+// at most once per game, once it fired it is skipped until the game ends.
+type Rules struct {
+	rules []compiledRule
+	// fired holds the indexes of the rules that bet in a game by game id
+	fired map[int]map[int]bool
+
+	// game and balance of the update being evaluated, read by the functions
+	mu      sync.Mutex
+	game    *client.Game
+	balance float32
+}
+
+func NewRules(rules []Rule) (*Rules, error) {
+	r := &Rules{fired: map[int]map[int]bool{}}
+	functions := map[string]govaluate.ExpressionFunction{
+		"bet_for_percent": func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("bet_for_percent takes one argument")
+			}
+			percent, ok := args[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("bet_for_percent argument must be a number")
+			}
+			return float64(r.game.PlanBets(float32(percent), r.balance).Total), nil
+		},
+		"min_bet": func(args ...interface{}) (interface{}, error) {
+			return float64(r.game.MinBet), nil
+		},
+		"max_bet": func(args ...interface{}) (interface{}, error) {
+			return float64(r.game.MaxBet), nil
+		},
+	}
+
+	for i, rule := range rules {
+		when, err := govaluate.NewEvaluableExpressionWithFunctions(rule.When, functions)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d condition: %w", i+1, err)
+		}
+		bet, err := govaluate.NewEvaluableExpressionWithFunctions(rule.Bet, functions)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d bet: %w", i+1, err)
+		}
+		r.rules = append(r.rules, compiledRule{source: rule, when: when, bet: bet})
+	}
+	return r, nil
+}
+
+func (r *Rules) Name() string {
+	return fmt.Sprintf("rules(%d)", len(r.rules))
+}
+
+func (r *Rules) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reason == client.GameEnd {
+		delete(r.fired, game.Id)
+		return nil
+	}
+	r.game = game
+	r.balance = balance
+
+	params := map[string]interface{}{
+		"bank":      float64(game.Bank),
+		"time_left": game.TimeLeft().Seconds(),
+		"players":   float64(game.Players()),
+		"my_chance": float64(game.WinChance()),
+		"my_stake":  float64(game.BetNow),
+		"room":      game.Room,
+		"room_id":   float64(game.RoomId),
+		"balance":   float64(balance),
+	}
+	for i, rule := range r.rules {
+		if r.fired[game.Id][i] {
+			continue
+		}
+		matched, err := rule.when.Evaluate(params)
+		if err != nil {
+			fmt.Printf("rule %d condition error: %s\n", i+1, err)
+			continue
+		}
+		if ok, _ := matched.(bool); !ok {
+			continue
+		}
+		value, err := rule.bet.Evaluate(params)
+		if err != nil {
+			fmt.Printf("rule %d bet error: %s\n", i+1, err)
+			return nil
+		}
+		amount, ok := value.(float64)
+		if !ok {
+			fmt.Printf("rule %d bet is not a number: %v\n", i+1, value)
+			return nil
+		}
+		decisions := splitStake(game, float32(amount), balance, fmt.Sprintf("rule %d: %s", i+1, rule.source.When))
+		if len(decisions) > 0 {
+			if r.fired[game.Id] == nil {
+				r.fired[game.Id] = map[int]bool{}
+			}
+			r.fired[game.Id][i] = true
+		}
+		return decisions
+	}
+	return nil
+}
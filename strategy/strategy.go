@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"math"
+	"time"
+
+	"github.com/Qwerty10291/csgf_bot/client"
+)
+
+type Decision struct {
+	Amount float32
+	Reason string
+}
+
+// Strategy receives every game update and returns the bets to place now.
+// Decisions returned for GameEnd are ignored.
+type Strategy interface {
+	Name() string
+	Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision
+}
+
+// Timed strategies still see every update, but the engine only places
+// their bets once, BetBefore the end of the countdown.
+type Timed interface {
+	Strategy
+	BetBefore() time.Duration
+}
+
+type lastSeconds struct {
+	Strategy
+	before time.Duration
+}
+
+func LastSeconds(s Strategy, before time.Duration) Strategy {
+	return &lastSeconds{Strategy: s, before: before}
+}
+
+func (l *lastSeconds) BetBefore() time.Duration {
+	return l.before
+}
+
+// roomFilter limits a strategy to the given rooms.
+type roomFilter struct {
+	Strategy
+	rooms map[string]bool
+}
+
+func OnlyRooms(s Strategy, rooms ...string) Strategy {
+	if len(rooms) == 0 {
+		return s
+	}
+	filter := &roomFilter{Strategy: s, rooms: map[string]bool{}}
+	for _, room := range rooms {
+		filter.rooms[room] = true
+	}
+	return filter
+}
+
+func (f *roomFilter) Decide(game *client.Game, reason client.GameUpdateReason, balance float32) []Decision {
+	if !f.rooms[game.Room] {
+		return nil
+	}
+	return f.Strategy.Decide(game, reason, balance)
+}
+
+// clampStake fits amount into the game limits, zero means no legal stake.
+func clampStake(game *client.Game, amount float32, balance float32) float32 {
+	if amount > game.MaxBet {
+		amount = game.MaxBet
+	}
+	if game.Bank+amount > game.MaxBank {
+		amount = game.MaxBank - game.Bank
+	}
+	if amount > balance {
+		amount = balance
+	}
+	if amount < game.MinBet {
+		return 0
+	}
+	return amount
+}
+
+// splitStake turns a total stake into bets of at most MaxBet.
+func splitStake(game *client.Game, amount float32, balance float32, reason string) []Decision {
+	if room := game.MaxBank - game.Bank; amount > room {
+		amount = room
+	}
+	if amount > balance {
+		amount = balance
+	}
+	amount = float32(math.Floor(float64(amount)*100) / 100)
+
+	decisions := []Decision{}
+	for amount >= game.MinBet {
+		bet := amount
+		if bet > game.MaxBet {
+			bet = game.MaxBet
+		}
+		decisions = append(decisions, Decision{Amount: bet, Reason: reason})
+		amount -= bet
+	}
+	return decisions
+}